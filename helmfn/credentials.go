@@ -0,0 +1,93 @@
+package helmfn
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/providers/argocd"
+)
+
+// repositoryCredentialKeys maps the keys of the Secrets referenced by spec.chart.auth to the keys of an
+// ArgoCD repository Secret
+var repositoryCredentialKeys = map[string]string{
+	"username": "username",
+	"password": "password",
+	"tls.crt":  "tlsClientCertData",
+	"tls.key":  "tlsClientCertKey",
+}
+
+// fillRepositoryCredentials copies the credentials of the chart repository into the generated ArgoCD
+// repository Secret. The Secrets referenced by spec.chart.auth have to be in the package, otherwise the
+// repository Secret is left without credentials and a warning is reported.
+func fillRepositoryCredentials(rl *fn.ResourceList, objs []*fn.KubeObject, helmRelease *types.HelmRelease) error {
+	auth := helmRelease.Spec.Chart.Auth
+	if auth == nil {
+		return nil
+	}
+
+	for _, obj := range objs {
+		if !obj.IsGVK("", "v1", "Secret") || obj.GetLabel(argocd.SecretTypeLabel) != "repository" {
+			continue
+		}
+
+		for _, name := range []string{auth.SecretName, auth.CertSecretName} {
+			if name == "" {
+				continue
+			}
+
+			data, err := secretData(rl, name, helmRelease.ObjectMeta.Namespace)
+			if err != nil {
+				return err
+			}
+			if data == nil {
+				rl.Results.Warningf("the Secret %s/%s is not in the package, the ArgoCD repository Secret %s has no credentials from it", helmRelease.ObjectMeta.Namespace, name, obj.GetName())
+				continue
+			}
+
+			for _, key := range sortedKeys(repositoryCredentialKeys) {
+				value, ok := data[key]
+				if !ok {
+					continue
+				}
+				if err := obj.SetNestedString(value, "stringData", repositoryCredentialKeys[key]); err != nil {
+					return fmt.Errorf("failed to set %s: %w", repositoryCredentialKeys[key], err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// secretData returns the decoded data of the named Secret in the resource list, or nil when it is absent
+func secretData(rl *fn.ResourceList, name, namespace string) (map[string]string, error) {
+	for _, item := range rl.Items {
+		if !item.IsGVK("", "v1", "Secret") || item.GetName() != name || item.GetNamespace() != namespace {
+			continue
+		}
+
+		data := map[string]string{}
+		encoded, _, err := item.NestedStringMap("data")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read data of Secret %s/%s: %w", namespace, name, err)
+		}
+		for k, v := range encoded {
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %s of Secret %s/%s: %w", k, namespace, name, err)
+			}
+			data[k] = string(decoded)
+		}
+
+		plain, _, err := item.NestedStringMap("stringData")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stringData of Secret %s/%s: %w", namespace, name, err)
+		}
+		for k, v := range plain {
+			data[k] = v
+		}
+		return data, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,67 @@
+package helmfn
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+// TestProcessFillsRepositoryCredentials tests that the ArgoCD repository Secret gets the credentials of the
+// Secrets referenced by spec.chart.auth
+func TestProcessFillsRepositoryCredentials(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	auth := map[string]interface{}{"secretName": "repo-auth", "certSecretName": "repo-tls"}
+	if err := example.Release.SetNestedField(auth, "spec", "chart", "auth"); err != nil {
+		t.Fatalf("Failed to set auth: %v", err)
+	}
+
+	// The basic auth Secret is in the package, base64 encoded, the TLS Secret is not
+	repoAuth, err := fn.ParseKubeObject([]byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: repo-auth
+  namespace: my-system
+data:
+  username: YWRtaW4=
+stringData:
+  password: s3cr3t
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse Secret: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	rl.Items = append(rl.Items, repoAuth)
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	var repoSecret *fn.KubeObject
+	for _, item := range rl.Items {
+		if item.GetLabel("argocd.argoproj.io/secret-type") == "repository" {
+			repoSecret = item
+		}
+	}
+	if repoSecret == nil {
+		t.Fatal("Expected an ArgoCD repository Secret in the output")
+	}
+
+	for key, expected := range map[string]string{"url": "https://helm.github.io/examples", "username": "admin", "password": "s3cr3t"} {
+		if value, _, _ := repoSecret.NestedString("stringData", key); value != expected {
+			t.Errorf("Expected %s '%s', got '%s'", key, expected, value)
+		}
+	}
+
+	if len(rl.Results) != 1 || !strings.Contains(rl.Results[0].Message, "my-system/repo-tls") {
+		t.Errorf("Expected a single warning about the missing Secret my-system/repo-tls, got %v", rl.Results)
+	}
+}
@@ -0,0 +1,127 @@
+package helmfn
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+)
+
+// SyncWaveAnnotation orders the ArgoCD Applications of an app of apps, lower waves are synced first
+const SyncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+
+// dependencySupport lists the providers which map spec.dependsOn
+var dependencySupport = []string{"argocd", "crossplane", "fluxcd", "helmfile", "sveltos"}
+
+// releaseDependencies returns the dependency graph of the HelmRelease in the functionConfig and of every
+// HelmRelease in the resource list, keyed by namespace/name. It fails when the dependencies form a cycle.
+func releaseDependencies(rl *fn.ResourceList, helmRelease *types.HelmRelease) (map[string][]string, error) {
+	graph := map[string][]string{}
+	for _, item := range rl.Items {
+		if item.GetAPIVersion() != "krm.kubed.io" || item.GetKind() != "HelmRelease" {
+			continue
+		}
+
+		release, err := parseHelmRelease(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HelmRelease %s: %w", item.ShortString(), err)
+		}
+		graph[releaseKey(release)] = dependencyKeys(release)
+	}
+
+	// The functionConfig wins over a stale copy of itself in the package
+	graph[releaseKey(helmRelease)] = dependencyKeys(helmRelease)
+
+	if cycle := findCycle(graph); cycle != nil {
+		return nil, fmt.Errorf("dependency cycle between HelmReleases: %s", strings.Join(cycle, " -> "))
+	}
+
+	return graph, nil
+}
+
+// dependencyKeys returns the namespace/name keys of the releases a HelmRelease depends on
+func dependencyKeys(helmRelease *types.HelmRelease) []string {
+	keys := make([]string, 0, len(helmRelease.Spec.DependsOn))
+	for _, dependency := range helmRelease.Spec.DependsOn {
+		namespace := dependency.Namespace
+		if namespace == "" {
+			namespace = helmRelease.ObjectMeta.Namespace
+		}
+		keys = append(keys, namespace+"/"+dependency.Name)
+	}
+	return keys
+}
+
+// findCycle returns the releases forming a dependency cycle, starting and ending with the same release,
+// or nil when the graph has none. Releases are visited in sorted order so the reported cycle is stable.
+func findCycle(graph map[string][]string) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+
+	var path []string
+	var visit func(key string) []string
+	visit = func(key string) []string {
+		switch state[key] {
+		case visiting:
+			start := slices.Index(path, key)
+			return append(slices.Clone(path[start:]), key)
+		case visited:
+			return nil
+		}
+
+		state[key] = visiting
+		path = append(path, key)
+		for _, dependency := range graph[key] {
+			if cycle := visit(dependency); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[key] = visited
+		return nil
+	}
+
+	for _, key := range sortedKeys(graph) {
+		if cycle := visit(key); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// syncWave returns the length of the longest dependency chain below a release, so every release is in a
+// later wave than all of its dependencies. Releases outside of the package are in wave 0. The wave of every
+// release is only computed once, shared dependencies would otherwise be walked once per path to them.
+func syncWave(graph map[string][]string, key string) int {
+	waves := map[string]int{}
+
+	var wave func(key string) int
+	wave = func(key string) int {
+		if w, ok := waves[key]; ok {
+			return w
+		}
+		w := 0
+		for _, dependency := range graph[key] {
+			w = max(w, wave(dependency)+1)
+		}
+		waves[key] = w
+		return w
+	}
+
+	return wave(key)
+}
+
+// applyDependencyOrdering orders the resources generated for providers whose ordering depends on the other
+// releases in the package. Dependencies known to the HelmRelease alone are mapped by the providers.
+func applyDependencyOrdering(objs []*fn.KubeObject, provider string, wave int) error {
+	if provider != "argocd" || wave == 0 || len(objs) == 0 {
+		return nil
+	}
+	return objs[0].SetAnnotation(SyncWaveAnnotation, strconv.Itoa(wave))
+}
@@ -0,0 +1,131 @@
+package helmfn
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name     string
+		graph    map[string][]string
+		expected []string
+	}{
+		{
+			name:  "chain",
+			graph: map[string][]string{"a/app": {"a/ingress"}, "a/ingress": {"a/cert-manager"}, "a/cert-manager": nil},
+		},
+		{
+			name:  "unknown dependency",
+			graph: map[string][]string{"a/app": {"b/external"}},
+		},
+		{
+			name:     "cycle",
+			graph:    map[string][]string{"a/app": {"a/ingress"}, "a/ingress": {"a/cert-manager"}, "a/cert-manager": {"a/ingress"}},
+			expected: []string{"a/ingress", "a/cert-manager", "a/ingress"},
+		},
+		{
+			name:     "self",
+			graph:    map[string][]string{"a/app": {"a/app"}},
+			expected: []string{"a/app", "a/app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cycle := findCycle(tt.graph); !reflect.DeepEqual(cycle, tt.expected) {
+				t.Errorf("Expected cycle %v, got %v", tt.expected, cycle)
+			}
+		})
+	}
+}
+
+func TestSyncWave(t *testing.T) {
+	graph := map[string][]string{
+		"a/app":          {"a/ingress", "a/cert-manager"},
+		"a/ingress":      {"a/cert-manager"},
+		"a/cert-manager": nil,
+		"a/other":        {"b/external"},
+	}
+
+	expected := map[string]int{"a/app": 2, "a/ingress": 1, "a/cert-manager": 0, "a/other": 1}
+	for key, wave := range expected {
+		if got := syncWave(graph, key); got != wave {
+			t.Errorf("Expected wave %d for %s, got %d", wave, key, got)
+		}
+	}
+}
+
+// TestSyncWaveSharedDependencies tests a chain of diamonds, whose paths double at every level
+func TestSyncWaveSharedDependencies(t *testing.T) {
+	const levels = 64
+	graph := map[string][]string{"a/level-0": nil}
+	for i := 1; i <= levels; i++ {
+		previous := fmt.Sprintf("a/level-%d", i-1)
+		left, right := fmt.Sprintf("a/left-%d", i), fmt.Sprintf("a/right-%d", i)
+		graph[left] = []string{previous}
+		graph[right] = []string{previous}
+		graph[fmt.Sprintf("a/level-%d", i)] = []string{left, right}
+	}
+
+	if got := syncWave(graph, fmt.Sprintf("a/level-%d", levels)); got != 2*levels {
+		t.Errorf("Expected wave %d, got %d", 2*levels, got)
+	}
+}
+
+// TestProcessDependsOn tests the ordering of a release after the other releases of the package
+func TestProcessDependsOn(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	dependsOn := []interface{}{map[string]interface{}{"name": "ingress"}}
+	if err := example.Release.SetNestedField(dependsOn, "spec", "dependsOn"); err != nil {
+		t.Fatalf("Failed to set dependsOn: %v", err)
+	}
+
+	ingress, err := fn.ParseKubeObject([]byte(`apiVersion: krm.kubed.io
+kind: HelmRelease
+metadata:
+  name: ingress
+  namespace: my-system
+spec:
+  provider: argocd
+  dependsOn:
+  - name: cert-manager
+    namespace: cert-manager
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	rl.Items = append(rl.Items, ingress)
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// cert-manager is outside of the package, ingress is in wave 1 and my-app after it
+	app := rl.Items[len(rl.Items)-1]
+	if app.GetKind() != "Application" || app.GetAnnotation(SyncWaveAnnotation) != "2" {
+		t.Errorf("Expected an Application in sync wave 2, got %s in wave '%s'", app.ShortString(), app.GetAnnotation(SyncWaveAnnotation))
+	}
+
+	// Closing the loop is reported as a cycle
+	if err := ingress.SetNestedField([]interface{}{map[string]interface{}{"name": "my-app"}}, "spec", "dependsOn"); err != nil {
+		t.Fatalf("Failed to set dependsOn: %v", err)
+	}
+	_, err = Process(rl)
+	if err == nil || !strings.Contains(err.Error(), "my-system/ingress -> my-system/my-app -> my-system/ingress") {
+		t.Errorf("Expected a dependency cycle error, got %v", err)
+	}
+}
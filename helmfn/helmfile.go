@@ -0,0 +1,227 @@
+package helmfn
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/providers/helmfile"
+	"sigs.k8s.io/yaml"
+)
+
+// helmfileObject wraps a helmfile.yaml in a ConfigMap, or turns it into a file of the package placed by
+// the path annotations. Files are not resources, they carry no metadata besides their path.
+func helmfileObject(helmRelease *types.HelmRelease, releaseHelmfile *helmfile.Helmfile) (*fn.KubeObject, error) {
+	provider := helmfile.NewHelmfileProvider()
+
+	var content interface{} = releaseHelmfile
+	if !provider.UsesFile(helmRelease) {
+		configMap, err := provider.GenerateConfigMap(helmRelease, releaseHelmfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate helmfile ConfigMap: %w", err)
+		}
+		content = configMap
+	}
+
+	objs, err := toKubeObjects(content)
+	if err != nil {
+		return nil, err
+	}
+	obj := objs[0]
+
+	if provider.UsesFile(helmRelease) {
+		file := helmfile.FilePath(helmRelease)
+		annotations := [][2]string{
+			{fn.PathAnnotation, file},
+			{fn.IndexAnnotation, "0"},
+			{legacyPathAnnotation, file},
+			{legacyIndexAnnotation, "0"},
+		}
+		for _, annotation := range annotations {
+			if err := obj.SetAnnotation(annotation[0], annotation[1]); err != nil {
+				return nil, fmt.Errorf("failed to set annotation %s on %s: %w", annotation[0], file, err)
+			}
+		}
+	}
+
+	return obj, nil
+}
+
+// mergePackageHelmfile merges the releases of the helmfile.yaml already in the package into the one
+// generated for the HelmRelease, so every release using the helmfile provider ends up in a single
+// helmfile.yaml whichever release generated it. The merged helmfile.yaml replaces the previous one when
+// the generated resources are reconciled.
+func mergePackageHelmfile(rl *fn.ResourceList, objs []*fn.KubeObject, helmRelease *types.HelmRelease) error {
+	if len(objs) == 0 {
+		return nil
+	}
+
+	for _, item := range rl.Items {
+		if !sameHelmfile(item, objs[0]) {
+			continue
+		}
+
+		existing, err := readHelmfile(item)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", helmfileName(item), err)
+		}
+		generated, err := readHelmfile(objs[0])
+		if err != nil {
+			return err
+		}
+
+		merged, err := helmfileObject(helmRelease, helmfile.Merge(existing, generated))
+		if err != nil {
+			return err
+		}
+		DebugLog("Merging HelmRelease %s into %s", releaseKey(helmRelease), helmfileName(item))
+
+		objs[0] = merged
+		return nil
+	}
+
+	return nil
+}
+
+// reconcileHelmfile returns what replaces a helmfile.yaml of the resource list, nil when it is pruned. The
+// helmfile.yaml is shared by the releases listed in it rather than owned by one. The replacement generated
+// for the release already holds the releases of the others. Without one the release stops using the
+// helmfile.yaml, so its release and the repositories no other release pulls from are removed.
+func reconcileHelmfile(item, replacement *fn.KubeObject, owner string) (*fn.KubeObject, error) {
+	if replacement != nil {
+		if err := preserveOrchestratorAnnotations(item, replacement); err != nil {
+			return nil, err
+		}
+		if !isPackageFile(replacement) {
+			merged, err := readHelmfile(replacement)
+			if err != nil {
+				return nil, err
+			}
+			if err := replacement.SetAnnotation(SharedByAnnotation, helmfileSharers(merged)); err != nil {
+				return nil, err
+			}
+		}
+		DebugLog("Replacing %s with the release of HelmRelease %s merged in", helmfileName(item), owner)
+		return replacement, nil
+	}
+
+	existing, err := readHelmfile(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", helmfileName(item), err)
+	}
+
+	namespace, name, _ := strings.Cut(owner, "/")
+	remaining := helmfile.Remove(existing, namespace, name)
+	switch {
+	case len(remaining.Releases) == len(existing.Releases):
+		return item, nil
+	case len(remaining.Releases) == 0:
+		DebugLog("Pruning %s no longer used by any HelmRelease", helmfileName(item))
+		return nil, nil
+	}
+
+	DebugLog("Removing HelmRelease %s from %s", owner, helmfileName(item))
+	return writeHelmfile(item, remaining)
+}
+
+// writeHelmfile returns the ConfigMap or file holding a helmfile.yaml with its content replaced
+func writeHelmfile(item *fn.KubeObject, content *helmfile.Helmfile) (*fn.KubeObject, error) {
+	if !isPackageFile(item) {
+		contentBytes, err := yaml.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal helmfile.yaml: %w", err)
+		}
+		if err := item.SetNestedString(string(contentBytes), "data", helmfile.HelmfileKey); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", helmfile.HelmfileKey, err)
+		}
+		if err := item.SetAnnotation(SharedByAnnotation, helmfileSharers(content)); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+
+	objs, err := toKubeObjects(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := preserveOrchestratorAnnotations(item, objs[0]); err != nil {
+		return nil, err
+	}
+	return objs[0], nil
+}
+
+// helmfileSharers returns the namespace/name of the releases of a helmfile.yaml, sorted and comma separated
+func helmfileSharers(content *helmfile.Helmfile) string {
+	sharers := make([]string, 0, len(content.Releases))
+	for _, release := range content.Releases {
+		sharers = append(sharers, release.Namespace+"/"+release.Name)
+	}
+	slices.Sort(sharers)
+	return strings.Join(sharers, ",")
+}
+
+// isHelmfile reports whether obj holds a helmfile.yaml of the helmfile provider, a generated ConfigMap or
+// a file of the package listing releases
+func isHelmfile(obj *fn.KubeObject) bool {
+	if isPackageFile(obj) {
+		content, err := readHelmfile(obj)
+		return err == nil && len(content.Releases) > 0
+	}
+	return obj.GetLabel(ManagedByLabel) == ManagedByValue && holdsHelmfile(obj)
+}
+
+// holdsHelmfile reports whether obj is a ConfigMap holding a helmfile.yaml
+func holdsHelmfile(obj *fn.KubeObject) bool {
+	if !obj.IsGVK("", "v1", "ConfigMap") {
+		return false
+	}
+	_, found, _ := obj.NestedString("data", helmfile.HelmfileKey)
+	return found
+}
+
+// sameHelmfile reports whether item holds the same helmfile.yaml as the generated one, the same file or
+// the same ConfigMap
+func sameHelmfile(item, generated *fn.KubeObject) bool {
+	if isPackageFile(generated) {
+		return isPackageFile(item) && filePath(item) == filePath(generated)
+	}
+	return resourceID(item) == resourceID(generated) && item.GetLabel(ManagedByLabel) == ManagedByValue
+}
+
+// readHelmfile reads the helmfile.yaml held by a ConfigMap or a file
+func readHelmfile(obj *fn.KubeObject) (*helmfile.Helmfile, error) {
+	if isPackageFile(obj) {
+		return helmfile.Parse(obj.String())
+	}
+
+	data, _, err := obj.NestedString("data", helmfile.HelmfileKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", helmfile.HelmfileKey, err)
+	}
+	return helmfile.Parse(data)
+}
+
+// isPackageFile reports whether obj is a plain file of the package rather than a resource
+func isPackageFile(obj *fn.KubeObject) bool {
+	return obj.GetAPIVersion() == "" && obj.GetKind() == ""
+}
+
+// filePath returns the path of a file of the package
+func filePath(obj *fn.KubeObject) string {
+	for _, k := range []string{fn.PathAnnotation, legacyPathAnnotation} {
+		if p := obj.GetAnnotation(k); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// helmfileName describes the ConfigMap or file holding a helmfile.yaml in messages
+func helmfileName(obj *fn.KubeObject) string {
+	if isPackageFile(obj) {
+		return filePath(obj)
+	}
+	return obj.ShortString()
+}
@@ -0,0 +1,226 @@
+package helmfn
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/providers/helmfile"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+// certManagerRelease is a second HelmRelease of the helmfile example package
+const certManagerRelease = `apiVersion: krm.kubed.io
+kind: HelmRelease
+metadata:
+  name: cert-manager
+  namespace: my-system
+spec:
+  provider: helmfile
+  chart:
+    name: cert-manager
+    version: v1.14.0
+    repo: https://charts.jetstack.io
+`
+
+// TestProcessMergesHelmfiles tests that the releases of a package using the helmfile provider share a
+// single helmfile.yaml, whichever release ran last
+func TestProcessMergesHelmfiles(t *testing.T) {
+	// Load the helmfile example files
+	exampleDir := filepath.Join("..", "examples", "helmfile")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	certManager, err := fn.ParseKubeObject([]byte(certManagerRelease))
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	for _, release := range []*fn.KubeObject{example.Release, certManager, example.Release} {
+		rl.FunctionConfig = release
+		if _, err := Process(rl); err != nil {
+			t.Fatalf("Process of %s failed: %v", release.GetName(), err)
+		}
+	}
+
+	var helmfiles []*fn.KubeObject
+	for _, item := range rl.Items {
+		if item.GetKind() == "ConfigMap" && item.GetName() == helmfile.DefaultName {
+			helmfiles = append(helmfiles, item)
+		}
+	}
+	if len(helmfiles) != 1 {
+		t.Fatalf("Expected a single helmfile ConfigMap, got %d", len(helmfiles))
+	}
+
+	merged, err := readHelmfile(helmfiles[0])
+	if err != nil {
+		t.Fatalf("Failed to read helmfile.yaml: %v", err)
+	}
+
+	// The release which ran again keeps its place
+	if len(merged.Releases) != 2 || merged.Releases[0].Name != "my-app" || merged.Releases[1].Name != "cert-manager" {
+		t.Errorf("Expected the releases my-app and cert-manager, got %+v", merged.Releases)
+	}
+	if len(merged.Repositories) != 2 {
+		t.Errorf("Expected a repository per chart repository, got %+v", merged.Repositories)
+	}
+
+	// Shared by every release in it rather than owned by the last one which ran
+	if owner := helmfiles[0].GetAnnotation(OriginReleaseAnnotation); owner != "" {
+		t.Errorf("Expected the helmfile to have no origin release, got '%s'", owner)
+	}
+	if sharers := helmfiles[0].GetAnnotation(SharedByAnnotation); sharers != "my-system/cert-manager,my-system/my-app" {
+		t.Errorf("Expected the helmfile to be shared by both releases, got '%s'", sharers)
+	}
+}
+
+// TestProcessHelmfileFile tests that the file mode writes a bare helmfile.yaml to its path, merged with
+// the helmfile.yaml already in the package
+func TestProcessHelmfileFile(t *testing.T) {
+	// Load the helmfile example files
+	exampleDir := filepath.Join("..", "examples", "helmfile")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	certManager, err := fn.ParseKubeObject([]byte(certManagerRelease))
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	for _, release := range []*fn.KubeObject{example.Release, certManager} {
+		if err := release.SetNestedField(map[string]interface{}{"mode": "file", "path": "deploy/helmfile.yaml"}, "spec", "helmfile"); err != nil {
+			t.Fatalf("Failed to set the helmfile mode: %v", err)
+		}
+	}
+
+	rl := example.CreateResourceList()
+	for _, release := range []*fn.KubeObject{example.Release, certManager, certManager} {
+		rl.FunctionConfig = release
+		if _, err := Process(rl); err != nil {
+			t.Fatalf("Process of %s failed: %v", release.GetName(), err)
+		}
+	}
+
+	// Original ConfigMap + helmfile.yaml
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output, got %d", len(rl.Items))
+	}
+
+	file := rl.Items[1]
+	if !isPackageFile(file) || filePath(file) != "deploy/helmfile.yaml" {
+		t.Fatalf("Expected the file deploy/helmfile.yaml, got %s", file.ShortString())
+	}
+
+	// Only the path annotations, helmfile rejects unknown fields
+	if len(file.GetLabels()) != 0 || len(file.GetAnnotations()) != 4 {
+		t.Errorf("Expected only the path and index annotations, got %v and %v", file.GetLabels(), file.GetAnnotations())
+	}
+
+	merged, err := readHelmfile(file)
+	if err != nil {
+		t.Fatalf("Failed to read helmfile.yaml: %v", err)
+	}
+	if len(merged.Releases) != 2 {
+		t.Errorf("Expected the releases my-app and cert-manager, got %+v", merged.Releases)
+	}
+
+	// A release switching away only removes itself from the file
+	if err := certManager.SetNestedString("fluxcd", "spec", "provider"); err != nil {
+		t.Fatalf("Failed to switch provider: %v", err)
+	}
+	rl.FunctionConfig = certManager
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process with fluxcd failed: %v", err)
+	}
+
+	file = rl.Items[1]
+	if !isPackageFile(file) || filePath(file) != "deploy/helmfile.yaml" {
+		t.Fatalf("Expected the file deploy/helmfile.yaml to stay, got %s", file.ShortString())
+	}
+	remaining, err := readHelmfile(file)
+	if err != nil {
+		t.Fatalf("Failed to read helmfile.yaml: %v", err)
+	}
+	if len(remaining.Releases) != 1 || remaining.Releases[0].Name != "my-app" || len(remaining.Repositories) != 1 {
+		t.Errorf("Expected only my-app and its repository, got %+v", remaining)
+	}
+}
+
+// TestProcessRemovesReleaseFromHelmfile tests that a release switching away from the helmfile provider only
+// removes its own release and repository from the shared helmfile.yaml
+func TestProcessRemovesReleaseFromHelmfile(t *testing.T) {
+	// Load the helmfile example files
+	exampleDir := filepath.Join("..", "examples", "helmfile")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	certManager, err := fn.ParseKubeObject([]byte(certManagerRelease))
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	for _, release := range []*fn.KubeObject{certManager, example.Release} {
+		rl.FunctionConfig = release
+		if _, err := Process(rl); err != nil {
+			t.Fatalf("Process of %s failed: %v", release.GetName(), err)
+		}
+	}
+
+	helmfileConfigMap := func() *fn.KubeObject {
+		for _, item := range rl.Items {
+			if item.GetKind() == "ConfigMap" && item.GetName() == helmfile.DefaultName {
+				return item
+			}
+		}
+		return nil
+	}
+
+	// my-app, which ran last, switches to fluxcd
+	if err := example.Release.SetNestedString("fluxcd", "spec", "provider"); err != nil {
+		t.Fatalf("Failed to switch provider: %v", err)
+	}
+	rl.FunctionConfig = example.Release
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process with fluxcd failed: %v", err)
+	}
+
+	configMap := helmfileConfigMap()
+	if configMap == nil {
+		t.Fatal("Expected the helmfile ConfigMap to stay while cert-manager uses it")
+	}
+	remaining, err := readHelmfile(configMap)
+	if err != nil {
+		t.Fatalf("Failed to read helmfile.yaml: %v", err)
+	}
+	if len(remaining.Releases) != 1 || remaining.Releases[0].Name != "cert-manager" {
+		t.Errorf("Expected only the cert-manager release, got %+v", remaining.Releases)
+	}
+	if len(remaining.Repositories) != 1 || remaining.Repositories[0].URL != "https://charts.jetstack.io" {
+		t.Errorf("Expected only the repository of cert-manager, got %+v", remaining.Repositories)
+	}
+	if sharers := configMap.GetAnnotation(SharedByAnnotation); sharers != "my-system/cert-manager" {
+		t.Errorf("Expected the helmfile to be shared by cert-manager only, got '%s'", sharers)
+	}
+
+	// The last release in the helmfile.yaml switches away as well
+	if err := certManager.SetNestedString("fluxcd", "spec", "provider"); err != nil {
+		t.Fatalf("Failed to switch provider: %v", err)
+	}
+	rl.FunctionConfig = certManager
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process with fluxcd failed: %v", err)
+	}
+
+	if configMap := helmfileConfigMap(); configMap != nil {
+		t.Errorf("Expected the helmfile ConfigMap to be pruned, got %s", configMap.ShortString())
+	}
+}
@@ -0,0 +1,104 @@
+package helmfn
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+)
+
+const (
+	// legacyPathAnnotation is the pre kpt v1 path annotation, still read by older orchestrators
+	legacyPathAnnotation = fn.ConfigPrefix + "path"
+	// legacyIndexAnnotation is the pre kpt v1 index annotation, still read by older orchestrators
+	legacyIndexAnnotation = fn.ConfigPrefix + "index"
+)
+
+// applyOutputLayout sets the path and index annotations of the generated resources according to the
+// output layout of the HelmRelease so kpt writes them to stable files. When the release has several
+// providers, each provider gets its own directory or file.
+func applyOutputLayout(objs []*fn.KubeObject, helmRelease *types.HelmRelease, perProvider bool) error {
+	layout := types.OutputLayoutResource
+	dir := releaseDir(helmRelease)
+	if output := helmRelease.Spec.Output; output != nil {
+		if output.Layout != "" {
+			layout = output.Layout
+		}
+		if output.Dir != "" {
+			dir = output.Dir
+		}
+	}
+
+	if layout == types.OutputLayoutNone {
+		return nil
+	}
+
+	// Index counts the resources already placed in each file
+	index := map[string]int{}
+	for _, obj := range objs {
+		// Files of the package are generated with their path
+		if isPackageFile(obj) {
+			continue
+		}
+
+		provider := ""
+		if perProvider {
+			provider = obj.GetAnnotation(OriginProviderAnnotation)
+		}
+
+		file, err := outputPath(obj, helmRelease.ObjectMeta.Name, provider, layout)
+		if err != nil {
+			return err
+		}
+		file = path.Join(dir, file)
+
+		idx := strconv.Itoa(index[file])
+		index[file]++
+
+		annotations := [][2]string{
+			{fn.PathAnnotation, file},
+			{fn.IndexAnnotation, idx},
+			{legacyPathAnnotation, file},
+			{legacyIndexAnnotation, idx},
+		}
+		for _, annotation := range annotations {
+			if err := obj.SetAnnotation(annotation[0], annotation[1]); err != nil {
+				return fmt.Errorf("failed to set annotation %s on %s: %w", annotation[0], obj.ShortString(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// outputPath returns the file, relative to the output directory, a resource is written to. A non empty
+// provider separates the files of each provider.
+func outputPath(obj *fn.KubeObject, release, provider, layout string) (string, error) {
+	kind := strings.ToLower(obj.GetKind())
+	switch layout {
+	case types.OutputLayoutResource:
+		return path.Join(release, provider, kind+"_"+obj.GetName()+".yaml"), nil
+	case types.OutputLayoutKind:
+		return path.Join(release, provider, kind+".yaml"), nil
+	case types.OutputLayoutRelease:
+		if provider != "" {
+			return path.Join(release, provider+".yaml"), nil
+		}
+		return release + ".yaml", nil
+	default:
+		return "", fmt.Errorf("unsupported output layout: %s", layout)
+	}
+}
+
+// releaseDir returns the directory of the file the HelmRelease was read from, if known
+func releaseDir(helmRelease *types.HelmRelease) string {
+	for _, k := range []string{fn.PathAnnotation, legacyPathAnnotation} {
+		if p, ok := helmRelease.ObjectMeta.Annotations[k]; ok && p != "" {
+			return path.Dir(p)
+		}
+	}
+	return ""
+}
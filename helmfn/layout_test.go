@@ -0,0 +1,105 @@
+package helmfn
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+// TestProcessOutputLayout tests the path and index annotations of each output layout
+func TestProcessOutputLayout(t *testing.T) {
+	tests := []struct {
+		layout  string
+		dir     string
+		paths   []string
+		indexes []string
+	}{
+		{
+			layout:  "",
+			paths:   []string{"my-app/helmrelease_my-app.yaml", "my-app/helmrepository_helm-github-io-examples.yaml"},
+			indexes: []string{"0", "0"},
+		},
+		{
+			layout:  "kind",
+			dir:     "generated",
+			paths:   []string{"generated/my-app/helmrelease.yaml", "generated/my-app/helmrepository.yaml"},
+			indexes: []string{"0", "0"},
+		},
+		{
+			layout:  "release",
+			paths:   []string{"my-app.yaml", "my-app.yaml"},
+			indexes: []string{"0", "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			// Load the fluxcd example files
+			exampleDir := filepath.Join("..", "examples", "fluxcd")
+			example, err := testutil.LoadExampleFiles(exampleDir)
+			if err != nil {
+				t.Fatalf("Failed to load example files: %v", err)
+			}
+
+			if tt.layout != "" {
+				if err := example.Release.SetNestedString(tt.layout, "spec", "output", "layout"); err != nil {
+					t.Fatalf("Failed to set output layout: %v", err)
+				}
+			}
+			if tt.dir != "" {
+				if err := example.Release.SetNestedString(tt.dir, "spec", "output", "dir"); err != nil {
+					t.Fatalf("Failed to set output dir: %v", err)
+				}
+			}
+
+			rl := example.CreateResourceList()
+			if _, err := Process(rl); err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+
+			// Skip the values ConfigMap
+			generated := rl.Items[1:]
+			if len(generated) != len(tt.paths) {
+				t.Fatalf("Expected %d generated items, got %d", len(tt.paths), len(generated))
+			}
+
+			for i, item := range generated {
+				if item.GetAnnotation(fn.PathAnnotation) != tt.paths[i] {
+					t.Errorf("Expected path '%s', got '%s'", tt.paths[i], item.GetAnnotation(fn.PathAnnotation))
+				}
+				if item.GetAnnotation(legacyPathAnnotation) != tt.paths[i] {
+					t.Errorf("Expected legacy path '%s', got '%s'", tt.paths[i], item.GetAnnotation(legacyPathAnnotation))
+				}
+				if item.GetAnnotation(fn.IndexAnnotation) != tt.indexes[i] {
+					t.Errorf("Expected index '%s', got '%s'", tt.indexes[i], item.GetAnnotation(fn.IndexAnnotation))
+				}
+			}
+		})
+	}
+}
+
+// TestProcessOutputLayoutFollowsReleaseFile tests that generated files are placed next to the HelmRelease file
+func TestProcessOutputLayoutFollowsReleaseFile(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	if err := example.Release.SetAnnotation(fn.PathAnnotation, "apps/release.yaml"); err != nil {
+		t.Fatalf("Failed to set path annotation: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	expected := "apps/my-app/application_my-app.yaml"
+	if path := rl.Items[1].GetAnnotation(fn.PathAnnotation); path != expected {
+		t.Errorf("Expected path '%s', got '%s'", expected, path)
+	}
+}
@@ -0,0 +1,177 @@
+package helmfn
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+)
+
+const (
+	// ManagedByLabel is the standard label marking the tool which manages a resource
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	// ManagedByValue is the value of ManagedByLabel on every generated resource
+	ManagedByValue = "krm-helm-fn"
+	// OriginReleaseAnnotation records the namespace/name of the HelmRelease a resource was generated from,
+	// together with ManagedByLabel it marks the resources owned by that release
+	OriginReleaseAnnotation = "krm.kubed.io/origin-release"
+	// OriginProviderAnnotation records the provider which generated a resource
+	OriginProviderAnnotation = "krm.kubed.io/origin-provider"
+	// SharedByAnnotation records the namespace/name of the HelmReleases sharing a resource, comma separated.
+	// Shared resources have no origin release, they are pruned once no release uses them.
+	SharedByAnnotation = "krm.kubed.io/shared-by"
+)
+
+// reservedMetadataKeys are never propagated, whatever the HelmRelease propagation settings are.
+// These belong to the orchestrator (kpt, kustomize, kubectl) or to this function itself.
+var reservedMetadataKeys = []string{
+	"config.kubernetes.io/*",
+	"internal.config.kubernetes.io/*",
+	"internal.kpt.dev/*",
+	"kubectl.kubernetes.io/last-applied-configuration",
+	"krm.kubed.io/*",
+	ManagedByLabel,
+}
+
+// applyReleaseMetadata copies the allowed labels and annotations of the HelmRelease onto the generated
+// resources and stamps each one with the managed-by label and the origin annotations. Shared resources
+// belong to no single release, they are only marked as shared by it. Files of the package are left alone,
+// they are not resources.
+func applyReleaseMetadata(objs []*fn.KubeObject, helmRelease *types.HelmRelease, provider string) error {
+	var labelFilter, annotationFilter *types.MetadataFilter
+	if helmRelease.Spec.Propagation != nil {
+		labelFilter = helmRelease.Spec.Propagation.Labels
+		annotationFilter = helmRelease.Spec.Propagation.Annotations
+	}
+
+	labels := filterMetadata(helmRelease.ObjectMeta.Labels, labelFilter)
+	annotations := filterMetadata(helmRelease.ObjectMeta.Annotations, annotationFilter)
+	origin := releaseKey(helmRelease)
+
+	for _, obj := range objs {
+		if isPackageFile(obj) {
+			continue
+		}
+
+		if isSharedResource(obj) {
+			if err := markShared(obj, origin, provider); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, k := range sortedKeys(labels) {
+			if err := obj.SetLabel(k, labels[k]); err != nil {
+				return fmt.Errorf("failed to set label %s on %s: %w", k, obj.ShortString(), err)
+			}
+		}
+		for _, k := range sortedKeys(annotations) {
+			if err := obj.SetAnnotation(k, annotations[k]); err != nil {
+				return fmt.Errorf("failed to set annotation %s on %s: %w", k, obj.ShortString(), err)
+			}
+		}
+
+		if err := obj.SetLabel(ManagedByLabel, ManagedByValue); err != nil {
+			return fmt.Errorf("failed to set label %s on %s: %w", ManagedByLabel, obj.ShortString(), err)
+		}
+		if err := obj.SetAnnotation(OriginReleaseAnnotation, origin); err != nil {
+			return fmt.Errorf("failed to set annotation %s on %s: %w", OriginReleaseAnnotation, obj.ShortString(), err)
+		}
+		if err := obj.SetAnnotation(OriginProviderAnnotation, provider); err != nil {
+			return fmt.Errorf("failed to set annotation %s on %s: %w", OriginProviderAnnotation, obj.ShortString(), err)
+		}
+	}
+
+	return nil
+}
+
+// markShared stamps a shared resource with the managed-by label, the release sharing it and the origin
+// provider. The other releases sharing it are added when it is reconciled with the resource list.
+func markShared(obj *fn.KubeObject, release, provider string) error {
+	if err := obj.SetLabel(ManagedByLabel, ManagedByValue); err != nil {
+		return fmt.Errorf("failed to set label %s on %s: %w", ManagedByLabel, obj.ShortString(), err)
+	}
+	if err := obj.SetAnnotation(SharedByAnnotation, release); err != nil {
+		return fmt.Errorf("failed to set annotation %s on %s: %w", SharedByAnnotation, obj.ShortString(), err)
+	}
+	if err := obj.SetAnnotation(OriginProviderAnnotation, provider); err != nil {
+		return fmt.Errorf("failed to set annotation %s on %s: %w", OriginProviderAnnotation, obj.ShortString(), err)
+	}
+	return nil
+}
+
+// filterMetadata returns the entries of metadata which pass the filter and are not reserved
+func filterMetadata(metadata map[string]string, filter *types.MetadataFilter) map[string]string {
+	result := map[string]string{}
+	if filter != nil && filter.Disabled {
+		return result
+	}
+
+	for k, v := range metadata {
+		if matchesAny(k, reservedMetadataKeys) {
+			continue
+		}
+		if filter != nil {
+			if len(filter.Allow) > 0 && !matchesAny(k, filter.Allow) {
+				continue
+			}
+			if matchesAny(k, filter.Deny) {
+				continue
+			}
+		}
+		result[k] = v
+	}
+
+	return result
+}
+
+// matchesAny reports whether key matches at least one of the glob patterns
+func matchesAny(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether key matches pattern, where * matches any run of characters. Unlike file
+// globs * also spans /, so * and app.* match prefixed keys such as app.example.com/team.
+func matchGlob(pattern, key string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == key
+	}
+
+	if !strings.HasPrefix(key, parts[0]) {
+		return false
+	}
+	key = key[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(key, part)
+		if i < 0 {
+			return false
+		}
+		key = key[i+len(part):]
+	}
+	return len(key) >= len(last) && strings.HasSuffix(key, last)
+}
+
+// releaseKey returns the namespace/name identifier of a HelmRelease
+func releaseKey(helmRelease *types.HelmRelease) string {
+	return helmRelease.ObjectMeta.Namespace + "/" + helmRelease.ObjectMeta.Name
+}
+
+// sortedKeys returns the keys of m in lexical order so output is deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,127 @@
+package helmfn
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+func TestFilterMetadata(t *testing.T) {
+	metadata := map[string]string{
+		"team":                               "platform",
+		"example.com/owner":                  "alice",
+		"example.com/secret":                 "hidden",
+		"config.kubernetes.io/function":      "container: ...",
+		"internal.config.kubernetes.io/path": "release.yaml",
+		"app.kubernetes.io/managed-by":       "kustomize",
+	}
+
+	// No filter propagates everything except reserved keys
+	result := filterMetadata(metadata, nil)
+	if len(result) != 3 {
+		t.Errorf("Expected 3 propagated keys, got %d: %v", len(result), result)
+	}
+	if _, ok := result["config.kubernetes.io/function"]; ok {
+		t.Error("Reserved key config.kubernetes.io/function should never be propagated")
+	}
+
+	// Allow and deny lists are applied with deny taking precedence
+	result = filterMetadata(metadata, &types.MetadataFilter{
+		Allow: []string{"example.com/*"},
+		Deny:  []string{"example.com/secret"},
+	})
+	if len(result) != 1 || result["example.com/owner"] != "alice" {
+		t.Errorf("Expected only example.com/owner to be propagated, got %v", result)
+	}
+
+	// Patterns span the prefix of a key
+	result = filterMetadata(metadata, &types.MetadataFilter{Allow: []string{"*"}, Deny: []string{"example.*"}})
+	if len(result) != 1 || result["team"] != "platform" {
+		t.Errorf("Expected only team to be propagated, got %v", result)
+	}
+
+	// Disabled propagates nothing
+	result = filterMetadata(metadata, &types.MetadataFilter{Disabled: true})
+	if len(result) != 0 {
+		t.Errorf("Expected no propagated keys when disabled, got %v", result)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		expected     bool
+	}{
+		{"*", "example.com/team", true},
+		{"app.*", "app.example.com/team", true},
+		{"*/team", "example.com/team", true},
+		{"example.com/*", "example.com/team", true},
+		{"*.example.com/*", "app.example.com/team", true},
+		{"example.com/*", "other.com/team", false},
+		{"*/team", "example.com/teams", false},
+		{"a*a", "a", false},
+		{"team", "team", true},
+		{"team", "teams", false},
+	}
+
+	for _, tt := range tests {
+		if matched := matchGlob(tt.pattern, tt.key); matched != tt.expected {
+			t.Errorf("matchGlob(%q, %q) = %v, expected %v", tt.pattern, tt.key, matched, tt.expected)
+		}
+	}
+}
+
+// TestProcessPropagatesReleaseMetadata tests that generated resources carry the release metadata
+func TestProcessPropagatesReleaseMetadata(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Label and annotate the release before processing
+	if err := example.Release.SetLabel("team", "platform"); err != nil {
+		t.Fatalf("Failed to set label: %v", err)
+	}
+	if err := example.Release.SetAnnotation("example.com/owner", "alice"); err != nil {
+		t.Fatalf("Failed to set annotation: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	var generatedApp *fn.KubeObject
+	for _, item := range rl.Items {
+		if item.GetKind() == "Application" {
+			generatedApp = item
+		}
+	}
+	if generatedApp == nil {
+		t.Fatal("No ArgoCD Application was generated")
+	}
+
+	if generatedApp.GetLabel("team") != "platform" {
+		t.Errorf("Expected label team=platform, got '%s'", generatedApp.GetLabel("team"))
+	}
+	if generatedApp.GetAnnotation("example.com/owner") != "alice" {
+		t.Errorf("Expected annotation example.com/owner=alice, got '%s'", generatedApp.GetAnnotation("example.com/owner"))
+	}
+	if generatedApp.GetAnnotation("config.kubernetes.io/function") != "" {
+		t.Error("Expected config.kubernetes.io/function annotation not to be propagated")
+	}
+	if generatedApp.GetLabel(ManagedByLabel) != ManagedByValue {
+		t.Errorf("Expected label %s=%s, got '%s'", ManagedByLabel, ManagedByValue, generatedApp.GetLabel(ManagedByLabel))
+	}
+	if generatedApp.GetAnnotation(OriginReleaseAnnotation) != "my-system/my-app" {
+		t.Errorf("Expected origin release 'my-system/my-app', got '%s'", generatedApp.GetAnnotation(OriginReleaseAnnotation))
+	}
+	if generatedApp.GetAnnotation(OriginProviderAnnotation) != "argocd" {
+		t.Errorf("Expected origin provider 'argocd', got '%s'", generatedApp.GetAnnotation(OriginProviderAnnotation))
+	}
+}
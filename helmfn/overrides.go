@@ -0,0 +1,186 @@
+package helmfn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"sigs.k8s.io/yaml"
+)
+
+// applyProviderOverrides applies the providerOverrides block of the given provider to the generated
+// resources. The override targets the resource of the configured kind, or the first generated resource
+// which is always the main resource of the provider.
+func applyProviderOverrides(objs []*fn.KubeObject, helmRelease *types.HelmRelease, provider string) error {
+	override, ok := helmRelease.Spec.ProviderOverrides[provider]
+	if !ok || len(objs) == 0 {
+		return nil
+	}
+
+	targets := []int{0}
+	if override.Kind != "" {
+		targets = nil
+		for i, obj := range objs {
+			if obj.GetKind() == override.Kind {
+				targets = append(targets, i)
+			}
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("provider %s does not generate a %s to override", provider, override.Kind)
+		}
+	}
+
+	for _, i := range targets {
+		DebugLog("Applying %s provider overrides to %s", provider, objs[i].ShortString())
+		obj, err := overrideObject(objs[i], override)
+		if err != nil {
+			return fmt.Errorf("failed to override %s: %w", objs[i].ShortString(), err)
+		}
+		objs[i] = obj
+	}
+
+	return nil
+}
+
+// overrideObject returns a copy of obj with the merge and the patches of the override applied
+func overrideObject(obj *fn.KubeObject, override types.ProviderOverride) (*fn.KubeObject, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(obj.String()), &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resource: %w", err)
+	}
+
+	if override.Merge != nil {
+		doc = mergePatch(doc, override.Merge)
+	}
+
+	for _, op := range override.Patches {
+		var err error
+		if doc, err = applyJSONPatch(doc, op); err != nil {
+			return nil, fmt.Errorf("failed to apply %s %s: %w", op.Op, op.Path, err)
+		}
+	}
+
+	docBytes, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal resource: %w", err)
+	}
+
+	return fn.ParseKubeObject(docBytes)
+}
+
+// mergePatch merges patch into target following JSON merge patch (RFC 7386), maps are merged
+// recursively, null removes a key and any other value replaces the target value
+func mergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = map[string]interface{}{}
+	}
+
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+			continue
+		}
+		targetMap[k] = mergePatch(targetMap[k], v)
+	}
+
+	return targetMap
+}
+
+// applyJSONPatch applies a single JSON patch (RFC 6902) operation to doc
+func applyJSONPatch(doc interface{}, op types.JSONPatchOperation) (interface{}, error) {
+	switch op.Op {
+	case "add", "remove", "replace":
+	default:
+		return nil, fmt.Errorf("unsupported patch operation: %s", op.Op)
+	}
+
+	if op.Path == "" {
+		if op.Op == "remove" {
+			return nil, fmt.Errorf("cannot remove the whole document")
+		}
+		return op.Value, nil
+	}
+	if !strings.HasPrefix(op.Path, "/") {
+		return nil, fmt.Errorf("path must start with /")
+	}
+
+	var tokens []string
+	for _, token := range strings.Split(op.Path[1:], "/") {
+		tokens = append(tokens, strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~"))
+	}
+
+	return patchNode(doc, tokens, op)
+}
+
+// patchNode walks down doc following the pointer tokens and applies op at the last one
+func patchNode(doc interface{}, tokens []string, op types.JSONPatchOperation) (interface{}, error) {
+	token, last := tokens[0], len(tokens) == 1
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, exists := node[token]
+		if !last {
+			if !exists {
+				return nil, fmt.Errorf("key %q not found", token)
+			}
+			patched, err := patchNode(child, tokens[1:], op)
+			if err != nil {
+				return nil, err
+			}
+			node[token] = patched
+			return node, nil
+		}
+
+		if op.Op != "add" && !exists {
+			return nil, fmt.Errorf("key %q not found", token)
+		}
+		if op.Op == "remove" {
+			delete(node, token)
+		} else {
+			node[token] = op.Value
+		}
+		return node, nil
+
+	case []interface{}:
+		if last && op.Op == "add" && token == "-" {
+			return append(node, op.Value), nil
+		}
+
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i > len(node) || (i == len(node) && !(last && op.Op == "add")) {
+			return nil, fmt.Errorf("index %q out of range", token)
+		}
+
+		if !last {
+			patched, err := patchNode(node[i], tokens[1:], op)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = patched
+			return node, nil
+		}
+
+		switch op.Op {
+		case "add":
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = op.Value
+		case "remove":
+			node = append(node[:i], node[i+1:]...)
+		default:
+			node[i] = op.Value
+		}
+		return node, nil
+
+	default:
+		return nil, fmt.Errorf("cannot traverse into %q", token)
+	}
+}
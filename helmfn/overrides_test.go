@@ -0,0 +1,143 @@
+package helmfn
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+	"sigs.k8s.io/yaml"
+)
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]interface{}{
+		"project": "default",
+		"source": map[string]interface{}{
+			"chart":          "hello-world",
+			"targetRevision": "0.1.0",
+		},
+	}
+	patch := map[string]interface{}{
+		"project": "platform",
+		"source": map[string]interface{}{
+			"targetRevision": nil,
+			"path":           "charts",
+		},
+	}
+
+	expected := map[string]interface{}{
+		"project": "platform",
+		"source": map[string]interface{}{
+			"chart": "hello-world",
+			"path":  "charts",
+		},
+	}
+
+	if result := mergePatch(target, patch); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"syncOptions": []interface{}{"CreateNamespace=true"},
+			"project":     "default",
+		},
+	}
+
+	ops := []types.JSONPatchOperation{
+		{Op: "add", Path: "/spec/syncOptions/-", Value: "ServerSideApply=true"},
+		{Op: "add", Path: "/spec/syncOptions/0", Value: "Validate=false"},
+		{Op: "replace", Path: "/spec/project", Value: "platform"},
+		{Op: "add", Path: "/spec/revisionHistoryLimit", Value: 3},
+		{Op: "remove", Path: "/spec/syncOptions/1"},
+	}
+
+	var result interface{} = doc
+	for _, op := range ops {
+		var err error
+		if result, err = applyJSONPatch(result, op); err != nil {
+			t.Fatalf("applyJSONPatch %s %s failed: %v", op.Op, op.Path, err)
+		}
+	}
+
+	expected := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"syncOptions":          []interface{}{"Validate=false", "ServerSideApply=true"},
+			"project":              "platform",
+			"revisionHistoryLimit": 3,
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// Errors are reported for missing keys and unsupported operations
+	if _, err := applyJSONPatch(doc, types.JSONPatchOperation{Op: "replace", Path: "/spec/missing", Value: 1}); err == nil {
+		t.Error("Expected an error replacing a missing key")
+	}
+	if _, err := applyJSONPatch(doc, types.JSONPatchOperation{Op: "move", Path: "/spec/project"}); err == nil {
+		t.Error("Expected an error for an unsupported operation")
+	}
+}
+
+// TestProcessProviderOverrides tests that the overrides of the selected provider reach the generated resource
+func TestProcessProviderOverrides(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	var overrides map[string]interface{}
+	err = yaml.Unmarshal([]byte(`
+fluxcd:
+  kind: HelmRepository
+  merge:
+    spec:
+      type: oci
+  patches:
+  - op: add
+    path: /spec/suspend
+    value: true
+argocd:
+  merge:
+    spec:
+      project: ignored
+`), &overrides)
+	if err != nil {
+		t.Fatalf("Failed to parse overrides: %v", err)
+	}
+	if err := example.Release.SetNestedField(overrides, "spec", "providerOverrides"); err != nil {
+		t.Fatalf("Failed to set provider overrides: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	var generatedRepo *fn.KubeObject
+	for _, item := range rl.Items {
+		if item.GetKind() == "HelmRepository" {
+			generatedRepo = item
+		}
+	}
+	if generatedRepo == nil {
+		t.Fatal("No FluxCD HelmRepository was generated")
+	}
+
+	if repoType, _, _ := generatedRepo.NestedString("spec", "type"); repoType != "oci" {
+		t.Errorf("Expected merged spec.type 'oci', got '%s'", repoType)
+	}
+	if suspend, _, _ := generatedRepo.NestedBool("spec", "suspend"); !suspend {
+		t.Error("Expected patched spec.suspend to be true")
+	}
+	if generatedRepo.GetLabel(ManagedByLabel) != ManagedByValue {
+		t.Error("Expected overridden resource to still carry the managed-by label")
+	}
+}
@@ -0,0 +1,220 @@
+package helmfn
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/providers/argocd"
+	"sigs.k8s.io/yaml"
+)
+
+// preservedAnnotationPrefixes are the orchestrator annotations carried over from a previously
+// generated resource to its replacement so kpt keeps writing it back to the same file
+var preservedAnnotationPrefixes = []string{
+	"internal.config.kubernetes.io/",
+	"config.kubernetes.io/path",
+	"config.kubernetes.io/index",
+}
+
+// reconcileGeneratedItems merges freshly generated resources into the resource list. Resources previously
+// generated for the same HelmRelease are replaced in place when they are generated again and pruned when
+// they are not, so running the function repeatedly (e.g. kpt fn render) never duplicates resources. Shared
+// resources, e.g. the HelmRepository of a chart repository used by several releases or the helmfile.yaml
+// every release using the helmfile provider is merged into, record every release using them and are only
+// pruned once none does.
+func reconcileGeneratedItems(rl *fn.ResourceList, helmRelease *types.HelmRelease, generated []*fn.KubeObject) error {
+	owner := releaseKey(helmRelease)
+
+	pending := map[string]*fn.KubeObject{}
+	for _, obj := range generated {
+		pending[resourceID(obj)] = obj
+	}
+
+	items := make([]*fn.KubeObject, 0, len(rl.Items)+len(generated))
+	for _, item := range rl.Items {
+		id := resourceID(item)
+
+		var reconcileShared func(item, replacement *fn.KubeObject, owner string) (*fn.KubeObject, error)
+		switch {
+		case isHelmfile(item):
+			reconcileShared = reconcileHelmfile
+		case item.GetLabel(ManagedByLabel) == ManagedByValue && isSharedResource(item):
+			reconcileShared = reconcileSharedItem
+		}
+		if reconcileShared != nil {
+			kept, err := reconcileShared(item, pending[id], owner)
+			if err != nil {
+				return err
+			}
+			if kept != nil {
+				items = append(items, kept)
+			}
+			delete(pending, id)
+			continue
+		}
+
+		if !isOwnedBy(item, owner) {
+			// Two releases generating a resource with the same identity would overwrite each other
+			if item.GetLabel(ManagedByLabel) == ManagedByValue && pending[id] != nil {
+				return fmt.Errorf("%s is generated by both HelmRelease %s and HelmRelease %s", item.ShortString(), item.GetAnnotation(OriginReleaseAnnotation), owner)
+			}
+			items = append(items, item)
+			continue
+		}
+
+		replacement, ok := pending[id]
+		if !ok {
+			DebugLog("Pruning %s no longer generated by HelmRelease %s", item.ShortString(), owner)
+			continue
+		}
+
+		if err := preserveOrchestratorAnnotations(item, replacement); err != nil {
+			return err
+		}
+		DebugLog("Replacing %s generated by HelmRelease %s", item.ShortString(), owner)
+		items = append(items, replacement)
+		delete(pending, id)
+	}
+
+	// Whatever was not replaced in place is new and goes to the end in generation order
+	for _, obj := range generated {
+		if _, ok := pending[resourceID(obj)]; ok {
+			items = append(items, obj)
+		}
+	}
+
+	rl.Items = items
+	return nil
+}
+
+// reconcileSharedItem returns what replaces a shared resource of the resource list, nil when it is pruned.
+// The replacement generated for the release is shared with the releases already using the resource, which
+// must have generated the same content. Without a replacement the release stops sharing the resource.
+func reconcileSharedItem(item, replacement *fn.KubeObject, owner string) (*fn.KubeObject, error) {
+	sharers := sharedBy(item)
+	others := slices.DeleteFunc(slices.Clone(sharers), func(sharer string) bool { return sharer == owner })
+
+	if replacement == nil {
+		switch {
+		case len(others) == len(sharers):
+			return item, nil
+		case len(others) == 0:
+			DebugLog("Pruning %s no longer shared by any HelmRelease", item.ShortString())
+			return nil, nil
+		}
+		DebugLog("Releasing %s from HelmRelease %s", item.ShortString(), owner)
+		if err := item.SetAnnotation(SharedByAnnotation, strings.Join(others, ",")); err != nil {
+			return nil, err
+		}
+		return item, nil
+	}
+
+	if len(others) > 0 {
+		same, err := sameContent(item, replacement)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			return nil, fmt.Errorf("%s is shared with HelmRelease %s, which generates it with different settings than HelmRelease %s", item.ShortString(), strings.Join(others, ", "), owner)
+		}
+	}
+
+	if err := preserveOrchestratorAnnotations(item, replacement); err != nil {
+		return nil, err
+	}
+	sharers = append(others, owner)
+	slices.Sort(sharers)
+	if err := replacement.SetAnnotation(SharedByAnnotation, strings.Join(sharers, ",")); err != nil {
+		return nil, err
+	}
+	DebugLog("Sharing %s between HelmReleases %s", item.ShortString(), strings.Join(sharers, ", "))
+	return replacement, nil
+}
+
+// isSharedResource reports whether obj is shared between the releases using it rather than owned by one,
+// e.g. the FluxCD HelmRepository or the ArgoCD repository Secret of a chart repository, or the ConfigMap
+// holding the helmfile.yaml every release using the helmfile provider is merged into
+func isSharedResource(obj *fn.KubeObject) bool {
+	gk := obj.GroupKind()
+	switch {
+	case gk.Group == "source.toolkit.fluxcd.io" && gk.Kind == "HelmRepository":
+		return true
+	case gk.Group == "" && gk.Kind == "Secret":
+		return obj.GetLabel(argocd.SecretTypeLabel) == "repository"
+	case gk.Group == "" && gk.Kind == "ConfigMap":
+		return holdsHelmfile(obj)
+	default:
+		return false
+	}
+}
+
+// sharedBy returns the releases sharing a resource. A resource shared before the releases were recorded
+// is only known to be used by the release which generated it first.
+func sharedBy(obj *fn.KubeObject) []string {
+	if sharers := obj.GetAnnotation(SharedByAnnotation); sharers != "" {
+		return strings.Split(sharers, ",")
+	}
+	if origin := obj.GetAnnotation(OriginReleaseAnnotation); origin != "" {
+		return []string{origin}
+	}
+	return nil
+}
+
+// sameContent reports whether two versions of a resource only differ in their apiVersion and metadata
+func sameContent(a, b *fn.KubeObject) (bool, error) {
+	var contents [2]map[string]interface{}
+	for i, obj := range []*fn.KubeObject{a, b} {
+		if err := yaml.Unmarshal([]byte(obj.String()), &contents[i]); err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", obj.ShortString(), err)
+		}
+		delete(contents[i], "apiVersion")
+		delete(contents[i], "metadata")
+	}
+	return reflect.DeepEqual(contents[0], contents[1]), nil
+}
+
+// isOwnedBy reports whether obj was generated by this function for the HelmRelease identified by owner
+func isOwnedBy(obj *fn.KubeObject, owner string) bool {
+	return obj.GetLabel(ManagedByLabel) == ManagedByValue && obj.GetAnnotation(OriginReleaseAnnotation) == owner
+}
+
+// resourceID identifies a resource by group, kind, namespace and name, ignoring the version so that
+// an apiVersion bump replaces the old resource instead of duplicating it. Files of the package are
+// identified by their path.
+func resourceID(obj *fn.KubeObject) string {
+	if isPackageFile(obj) {
+		return "file|" + filePath(obj)
+	}
+	gk := obj.GroupKind()
+	return strings.Join([]string{gk.Group, gk.Kind, obj.GetNamespace(), obj.GetName()}, "|")
+}
+
+// preserveOrchestratorAnnotations copies the path and index annotations of the previous resource to
+// its replacement. They win over the ones the output layout just set, so a resource moved to another
+// file by hand stays where it was moved.
+func preserveOrchestratorAnnotations(previous, replacement *fn.KubeObject) error {
+	annotations := previous.GetAnnotations()
+	for _, k := range sortedKeys(annotations) {
+		if !hasAnyPrefix(k, preservedAnnotationPrefixes) {
+			continue
+		}
+		if err := replacement.SetAnnotation(k, annotations[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasAnyPrefix reports whether s starts with one of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package helmfn
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kptdev/krm-functions-sdk/go/fn"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+// TestProcessIsIdempotent tests that running the function on its own output does not duplicate resources
+func TestProcessIsIdempotent(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Leave file placement to kpt so the preserved path annotation can be checked
+	if err := example.Release.SetNestedString("none", "spec", "output", "layout"); err != nil {
+		t.Fatalf("Failed to set output layout: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("First Process failed: %v", err)
+	}
+	firstCount := len(rl.Items)
+
+	// Simulate kpt writing the output to disk and reading it back
+	if err := rl.Items[1].SetAnnotation("internal.config.kubernetes.io/path", "flux/my-app.yaml"); err != nil {
+		t.Fatalf("Failed to set path annotation: %v", err)
+	}
+
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Second Process failed: %v", err)
+	}
+
+	if len(rl.Items) != firstCount {
+		t.Errorf("Expected %d items after re-running, got %d", firstCount, len(rl.Items))
+	}
+
+	if rl.Items[1].GetKind() != "HelmRelease" {
+		t.Errorf("Expected the HelmRelease to be replaced in place, got %s", rl.Items[1].GetKind())
+	}
+
+	if rl.Items[1].GetAnnotation("internal.config.kubernetes.io/path") != "flux/my-app.yaml" {
+		t.Errorf("Expected the path annotation to be preserved, got '%s'", rl.Items[1].GetAnnotation("internal.config.kubernetes.io/path"))
+	}
+}
+
+// TestProcessKeepsMovedResources tests that a generated resource moved to another file stays there when
+// the function runs again
+func TestProcessKeepsMovedResources(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("First Process failed: %v", err)
+	}
+
+	// Move the HelmRelease to another file the way kpt reads it back
+	for _, k := range []string{fn.PathAnnotation, legacyPathAnnotation} {
+		if err := rl.Items[1].SetAnnotation(k, "apps/my-app.yaml"); err != nil {
+			t.Fatalf("Failed to set annotation %s: %v", k, err)
+		}
+	}
+
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Second Process failed: %v", err)
+	}
+
+	for _, k := range []string{fn.PathAnnotation, legacyPathAnnotation} {
+		if path := rl.Items[1].GetAnnotation(k); path != "apps/my-app.yaml" {
+			t.Errorf("Expected %s to stay 'apps/my-app.yaml', got '%s'", k, path)
+		}
+	}
+
+	// Resources which were not moved keep the layout path
+	if path := rl.Items[2].GetAnnotation(fn.PathAnnotation); path != "my-app/helmrepository_helm-github-io-examples.yaml" {
+		t.Errorf("Expected the HelmRepository to keep its layout path, got '%s'", path)
+	}
+}
+
+// TestProcessPrunesPreviousProvider tests that switching providers removes the old provider resources
+func TestProcessPrunesPreviousProvider(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process with fluxcd failed: %v", err)
+	}
+
+	// Switch the release to another provider and run again
+	if err := rl.FunctionConfig.SetNestedString("rancher", "spec", "provider"); err != nil {
+		t.Fatalf("Failed to switch provider: %v", err)
+	}
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process with rancher failed: %v", err)
+	}
+
+	// Original ConfigMap + generated Rancher HelmChart
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items after switching provider, got %d", len(rl.Items))
+	}
+
+	for _, item := range rl.Items {
+		if item.GetAnnotation(OriginProviderAnnotation) == "fluxcd" {
+			t.Errorf("Expected FluxCD resource %s to be pruned", item.ShortString())
+		}
+	}
+
+	if rl.Items[1].GetKind() != "HelmChart" {
+		t.Errorf("Expected a Rancher HelmChart, got %s", rl.Items[1].GetKind())
+	}
+}
+
+// TestProcessSharesHelmRepository tests that releases using the same chart repository share one HelmRepository
+func TestProcessSharesHelmRepository(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Run a second release from the same repository on the output of the first
+	if err := rl.FunctionConfig.SetName("other-app"); err != nil {
+		t.Fatalf("Failed to set name: %v", err)
+	}
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + two HelmReleases + one shared HelmRepository
+	kinds := map[string]int{}
+	for _, item := range rl.Items {
+		kinds[item.GetKind()]++
+	}
+	if kinds["HelmRelease"] != 2 || kinds["HelmRepository"] != 1 {
+		t.Fatalf("Expected 2 HelmReleases and 1 HelmRepository, got %v", kinds)
+	}
+
+	// Referencing an existing HelmRepository generates none
+	sourceRef := map[string]interface{}{"name": "examples", "namespace": "flux-system"}
+	if err := rl.FunctionConfig.SetNestedField(sourceRef, "spec", "fluxcd", "sourceRef"); err != nil {
+		t.Fatalf("Failed to set sourceRef: %v", err)
+	}
+	if err := rl.FunctionConfig.SetName("third-app"); err != nil {
+		t.Fatalf("Failed to set name: %v", err)
+	}
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	generated := rl.Items[len(rl.Items)-1]
+	if generated.GetName() != "third-app" || generated.GetKind() != "HelmRelease" {
+		t.Fatalf("Expected the HelmRelease third-app last, got %s", generated.ShortString())
+	}
+	if name, _, _ := generated.NestedString("spec", "chart", "spec", "sourceRef", "name"); name != "examples" {
+		t.Errorf("Expected sourceRef name 'examples', got '%s'", name)
+	}
+	if namespace, _, _ := generated.NestedString("spec", "chart", "spec", "sourceRef", "namespace"); namespace != "flux-system" {
+		t.Errorf("Expected sourceRef namespace 'flux-system', got '%s'", namespace)
+	}
+}
+
+// TestProcessReleasesSharedHelmRepository tests that a shared HelmRepository stays while a release still
+// uses it and is pruned once none does
+func TestProcessReleasesSharedHelmRepository(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	otherApp, err := fn.ParseKubeObject([]byte(example.Release.String()))
+	if err != nil {
+		t.Fatalf("Failed to copy HelmRelease: %v", err)
+	}
+	if err := otherApp.SetName("other-app"); err != nil {
+		t.Fatalf("Failed to set name: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	for _, release := range []*fn.KubeObject{example.Release, otherApp} {
+		rl.FunctionConfig = release
+		if _, err := Process(rl); err != nil {
+			t.Fatalf("Process of %s failed: %v", release.GetName(), err)
+		}
+	}
+
+	helmRepository := func() *fn.KubeObject {
+		for _, item := range rl.Items {
+			if item.GetKind() == "HelmRepository" {
+				return item
+			}
+		}
+		return nil
+	}
+
+	repo := helmRepository()
+	if repo == nil {
+		t.Fatal("No HelmRepository was generated")
+	}
+	if sharers := repo.GetAnnotation(SharedByAnnotation); sharers != "my-system/my-app,my-system/other-app" {
+		t.Errorf("Expected the HelmRepository to be shared by both releases, got '%s'", sharers)
+	}
+	if origin := repo.GetAnnotation(OriginReleaseAnnotation); origin != "" {
+		t.Errorf("Expected the shared HelmRepository to have no origin release, got '%s'", origin)
+	}
+
+	// The release which generated the HelmRepository first switches away from fluxcd
+	if err := example.Release.SetNestedString("rancher", "spec", "provider"); err != nil {
+		t.Fatalf("Failed to switch provider: %v", err)
+	}
+	rl.FunctionConfig = example.Release
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process with rancher failed: %v", err)
+	}
+
+	repo = helmRepository()
+	if repo == nil {
+		t.Fatal("Expected the HelmRepository to stay while other-app uses it")
+	}
+	if sharers := repo.GetAnnotation(SharedByAnnotation); sharers != "my-system/other-app" {
+		t.Errorf("Expected the HelmRepository to be shared by other-app only, got '%s'", sharers)
+	}
+
+	// The last release using it switches away as well
+	if err := otherApp.SetNestedString("rancher", "spec", "provider"); err != nil {
+		t.Fatalf("Failed to switch provider: %v", err)
+	}
+	rl.FunctionConfig = otherApp
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process with rancher failed: %v", err)
+	}
+
+	if repo := helmRepository(); repo != nil {
+		t.Errorf("Expected the HelmRepository to be pruned, got %s", repo.ShortString())
+	}
+}
+
+// TestProcessRejectsConflictingSharedResources tests that releases cannot share a repository with different
+// credentials
+func TestProcessRejectsConflictingSharedResources(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// A second release of the same repository with credentials of its own
+	if err := rl.FunctionConfig.SetName("other-app"); err != nil {
+		t.Fatalf("Failed to set name: %v", err)
+	}
+	if err := rl.FunctionConfig.SetNestedString("other-credentials", "spec", "chart", "auth", "secretName"); err != nil {
+		t.Fatalf("Failed to set chart auth: %v", err)
+	}
+	if _, err := Process(rl); err == nil {
+		t.Error("Expected an error for a HelmRepository shared with different credentials")
+	}
+}
+
+// TestProcessRejectsResourcesGeneratedByOtherReleases tests that a release cannot generate a resource another
+// release already generated with the same identity
+func TestProcessRejectsResourcesGeneratedByOtherReleases(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// A release of the same name in another namespace generates the Application argocd/my-app as well
+	if err := rl.FunctionConfig.SetNamespace("other-system"); err != nil {
+		t.Fatalf("Failed to set namespace: %v", err)
+	}
+	_, err = Process(rl)
+	if err == nil {
+		t.Fatal("Expected an error for an Application generated by two releases")
+	}
+	for _, release := range []string{"my-system/my-app", "other-system/my-app"} {
+		if !strings.Contains(err.Error(), release) {
+			t.Errorf("Expected the error to name HelmRelease %s, got '%v'", release, err)
+		}
+	}
+}
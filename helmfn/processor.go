@@ -2,16 +2,29 @@ package helmfn
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/kptdev/krm-functions-sdk/go/fn"
 	"github.com/kubed-io/krm-helm-fn/helmfn/types"
 	"github.com/kubed-io/krm-helm-fn/providers/argocd"
+	"github.com/kubed-io/krm-helm-fn/providers/capi"
 	"github.com/kubed-io/krm-helm-fn/providers/crossplane"
+	"github.com/kubed-io/krm-helm-fn/providers/fleet"
 	"github.com/kubed-io/krm-helm-fn/providers/fluxcd"
+	"github.com/kubed-io/krm-helm-fn/providers/helmfile"
+	"github.com/kubed-io/krm-helm-fn/providers/kapp"
+	"github.com/kubed-io/krm-helm-fn/providers/kustomize"
+	"github.com/kubed-io/krm-helm-fn/providers/ocm"
 	"github.com/kubed-io/krm-helm-fn/providers/rancher"
+	"github.com/kubed-io/krm-helm-fn/providers/sveltos"
 	"sigs.k8s.io/yaml"
 )
 
+// valueFromSupport lists the providers which read spec.set values from Secrets and ConfigMaps
+var valueFromSupport = []string{"crossplane", "fluxcd"}
+
 // Process is the main entry point for the KRM function
 func Process(rl *fn.ResourceList) (bool, error) {
 	// Check if we have a functionConfig (HelmRelease)
@@ -31,37 +44,193 @@ func Process(rl *fn.ResourceList) (bool, error) {
 		return false, fmt.Errorf("failed to parse HelmRelease from functionConfig: %w", err)
 	}
 
-	DebugLog("Processing HelmRelease %s/%s with provider: %s", helmRelease.ObjectMeta.Namespace, helmRelease.ObjectMeta.Name, helmRelease.Spec.Provider)
+	providers, err := releaseProviders(helmRelease)
+	if err != nil {
+		return false, err
+	}
+
+	dependencies, err := releaseDependencies(rl, helmRelease)
+	if err != nil {
+		return false, err
+	}
+	wave := syncWave(dependencies, releaseKey(helmRelease))
+
+	DebugLog("Processing HelmRelease %s/%s with providers: %s", helmRelease.ObjectMeta.Namespace, helmRelease.ObjectMeta.Name, strings.Join(providers, ", "))
+
+	var generated []*fn.KubeObject
+	for _, provider := range providers {
+		objs, err := generateProviderResources(helmRelease, provider)
+		if err != nil {
+			return false, err
+		}
+
+		// Lifecycle settings degrade gracefully, the provider ignores what it cannot map
+		_, usage := supportKey(helmRelease, provider)
+		unsupported, err := unsupportedLifecycleFields(helmRelease, provider)
+		if err != nil {
+			return false, err
+		}
+		for _, field := range unsupported {
+			rl.Results.Warningf("%s does not support spec.lifecycle.%s, it is ignored", usage, field)
+		}
+
+		unsupported, err = unsupportedChartAuthFields(helmRelease, provider)
+		if err != nil {
+			return false, err
+		}
+		for _, field := range unsupported {
+			rl.Results.Warningf("%s does not support spec.chart.auth.%s, it is ignored", usage, field)
+		}
+
+		unsupported, err = unsupportedRancherFields(helmRelease, provider)
+		if err != nil {
+			return false, err
+		}
+		for _, field := range unsupported {
+			rl.Results.Warningf("%s does not support spec.rancher.%s, it is ignored", usage, field)
+		}
+
+		if len(helmRelease.Spec.DependsOn) > 0 && !slices.Contains(dependencySupport, provider) {
+			rl.Results.Warningf("the %s provider does not support spec.dependsOn, it is ignored", provider)
+		}
+
+		if slices.ContainsFunc(helmRelease.Spec.Set, func(set types.SetValue) bool { return set.ValueFrom != nil }) && !slices.Contains(valueFromSupport, provider) {
+			rl.Results.Warningf("the %s provider does not support spec.set values from Secrets or ConfigMaps, they are ignored", provider)
+		}
+
+		// ArgoCD reads repository credentials from its own namespace only
+		if provider == "argocd" {
+			if err := fillRepositoryCredentials(rl, objs, helmRelease); err != nil {
+				return false, fmt.Errorf("failed to fill repository credentials: %w", err)
+			}
+		}
+
+		// Every release using helmfile shares a single helmfile.yaml
+		if provider == "helmfile" {
+			if err := mergePackageHelmfile(rl, objs, helmRelease); err != nil {
+				return false, fmt.Errorf("failed to merge helmfile.yaml: %w", err)
+			}
+		}
+
+		// Order the release after the releases it depends on
+		if err := applyDependencyOrdering(objs, provider, wave); err != nil {
+			return false, fmt.Errorf("failed to apply dependency ordering: %w", err)
+		}
+
+		// Reach provider fields which have no portable equivalent
+		if err := applyProviderOverrides(objs, helmRelease, provider); err != nil {
+			return false, fmt.Errorf("failed to apply %s provider overrides: %w", provider, err)
+		}
+
+		// Propagate the release metadata and mark every generated resource as ours
+		if err := applyReleaseMetadata(objs, helmRelease, provider); err != nil {
+			return false, fmt.Errorf("failed to apply release metadata: %w", err)
+		}
+
+		generated = append(generated, objs...)
+	}
+
+	// Give every generated resource a stable file in the package
+	if err := applyOutputLayout(generated, helmRelease, len(providers) > 1); err != nil {
+		return false, fmt.Errorf("failed to apply output layout: %w", err)
+	}
+
+	// Replace or prune what a previous run generated for this release instead of duplicating it
+	if err := reconcileGeneratedItems(rl, helmRelease, generated); err != nil {
+		return false, fmt.Errorf("failed to reconcile generated resources: %w", err)
+	}
+
+	// Return true to indicate the function made changes to the resource list
+	// (added provider-specific resources like ArgoCD Application)
+	return true, nil
+}
+
+// releaseProviders returns the providers selected by the HelmRelease, sorted and without duplicates
+// so the output order does not depend on how the list was written
+func releaseProviders(helmRelease *types.HelmRelease) ([]string, error) {
+	if helmRelease.Spec.Provider != "" && len(helmRelease.Spec.Providers) > 0 {
+		return nil, fmt.Errorf("spec.provider and spec.providers are mutually exclusive")
+	}
+
+	providers := helmRelease.Spec.Providers
+	if helmRelease.Spec.Provider != "" {
+		providers = []string{helmRelease.Spec.Provider}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no provider selected, set spec.provider or spec.providers")
+	}
 
-	// Process based on provider
-	switch helmRelease.Spec.Provider {
+	providers = append([]string(nil), providers...)
+	sort.Strings(providers)
+	return slices.Compact(providers), nil
+}
+
+// generateProviderResources generates the resources of a single provider for the HelmRelease
+func generateProviderResources(helmRelease *types.HelmRelease, provider string) ([]*fn.KubeObject, error) {
+	var generated []*fn.KubeObject
+	var err error
+
+	switch provider {
 	case "argocd":
 		DebugLog("Processing ArgoCD provider")
-		if err := processArgoCDProvider(rl, helmRelease); err != nil {
-			return false, fmt.Errorf("failed to process ArgoCD provider: %w", err)
+		if generated, err = processArgoCDProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process ArgoCD provider: %w", err)
+		}
+	case "capi":
+		DebugLog("Processing Cluster API provider")
+		if generated, err = processCAPIProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process Cluster API provider: %w", err)
 		}
 	case "crossplane":
 		DebugLog("Processing Crossplane provider")
-		if err := processCrossplaneProvider(rl, helmRelease); err != nil {
-			return false, fmt.Errorf("failed to process Crossplane provider: %w", err)
+		if generated, err = processCrossplaneProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process Crossplane provider: %w", err)
+		}
+	case "fleet":
+		DebugLog("Processing Fleet provider")
+		if generated, err = processFleetProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process Fleet provider: %w", err)
 		}
 	case "fluxcd":
 		DebugLog("Processing FluxCD provider")
-		if err := processFluxCDProvider(rl, helmRelease); err != nil {
-			return false, fmt.Errorf("failed to process FluxCD provider: %w", err)
+		if generated, err = processFluxCDProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process FluxCD provider: %w", err)
+		}
+	case "helmfile":
+		DebugLog("Processing helmfile provider")
+		if generated, err = processHelmfileProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process helmfile provider: %w", err)
+		}
+	case "kapp":
+		DebugLog("Processing kapp provider")
+		if generated, err = processKappProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process kapp provider: %w", err)
+		}
+	case "kustomize":
+		DebugLog("Processing kustomize provider")
+		if generated, err = processKustomizeProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process kustomize provider: %w", err)
+		}
+	case "ocm":
+		DebugLog("Processing Open Cluster Management provider")
+		if generated, err = processOCMProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process Open Cluster Management provider: %w", err)
 		}
 	case "rancher":
 		DebugLog("Processing Rancher provider")
-		if err := processRancherProvider(rl, helmRelease); err != nil {
-			return false, fmt.Errorf("failed to process Rancher provider: %w", err)
+		if generated, err = processRancherProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process Rancher provider: %w", err)
+		}
+	case "sveltos":
+		DebugLog("Processing Sveltos provider")
+		if generated, err = processSveltosProvider(helmRelease); err != nil {
+			return nil, fmt.Errorf("failed to process Sveltos provider: %w", err)
 		}
 	default:
-		return false, fmt.Errorf("unsupported provider: %s", helmRelease.Spec.Provider)
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
 
-	// Return true to indicate the function made changes to the resource list
-	// (added provider-specific resources like ArgoCD Application)
-	return true, nil
+	return generated, nil
 }
 
 // parseHelmRelease converts a KRM object to HelmRelease struct
@@ -76,137 +245,333 @@ func parseHelmRelease(obj *fn.KubeObject) (*types.HelmRelease, error) {
 	return &helmRelease, nil
 }
 
+// toKubeObjects converts the resources generated by a provider to KubeObjects
+func toKubeObjects(resources ...interface{}) ([]*fn.KubeObject, error) {
+	objs := make([]*fn.KubeObject, 0, len(resources))
+	for _, resource := range resources {
+		resourceBytes, err := yaml.Marshal(resource)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %T: %w", resource, err)
+		}
+
+		obj, err := fn.ParseKubeObject(resourceBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %T to KubeObject: %w", resource, err)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
 // processArgoCDProvider handles ArgoCD provider processing
-func processArgoCDProvider(rl *fn.ResourceList, helmRelease *types.HelmRelease) error {
+func processArgoCDProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
 	// Create ArgoCD provider
 	provider := argocd.NewArgoCDProvider()
 
-	// Generate ArgoCD Application
-	app, err := provider.GenerateApplication(helmRelease)
-	if err != nil {
-		return fmt.Errorf("failed to generate ArgoCD application: %w", err)
-	}
+	var resources []interface{}
 
-	// Convert to KubeObject
-	appBytes, err := yaml.Marshal(app)
-	if err != nil {
-		return fmt.Errorf("failed to marshal ArgoCD application: %w", err)
+	// Generate an ArgoCD ApplicationSet when deploying to several clusters
+	if provider.UsesApplicationSet(helmRelease) {
+		appSet, err := provider.GenerateApplicationSet(helmRelease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ArgoCD application set: %w", err)
+		}
+
+		DebugLog("Generated ArgoCD ApplicationSet")
+
+		resources = append(resources, appSet)
+	} else {
+		// Generate ArgoCD Application
+		app, err := provider.GenerateApplication(helmRelease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ArgoCD application: %w", err)
+		}
+
+		resources = append(resources, app)
 	}
 
-	appObj, err := fn.ParseKubeObject(appBytes)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal to KubeObject: %w", err)
+	// Generate the AppProject the release is scoped to
+	if provider.UsesAppProject(helmRelease) {
+		appProject, err := provider.GenerateAppProject(helmRelease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ArgoCD app project: %w", err)
+		}
+
+		DebugLog("Generated ArgoCD AppProject")
+
+		resources = append(resources, appProject)
 	}
 
-	// Add the ArgoCD Application to the output items
-	rl.Items = append(rl.Items, appObj)
+	// Generate the Secret declaring the chart repository and its credentials
+	if provider.UsesRepositorySecret(helmRelease) {
+		repoSecret, err := provider.GenerateRepositorySecret(helmRelease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ArgoCD repository secret: %w", err)
+		}
+
+		DebugLog("Generated ArgoCD repository Secret")
 
-	return nil
+		resources = append(resources, repoSecret)
+	}
+
+	return toKubeObjects(resources...)
 }
 
 // processFluxCDProvider handles FluxCD provider processing
-func processFluxCDProvider(rl *fn.ResourceList, helmRelease *types.HelmRelease) error {
+func processFluxCDProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
 	// Create FluxCD provider
 	provider := fluxcd.NewFluxCDProvider()
 
 	// Generate FluxCD HelmRelease
 	fluxHelmRelease, err := provider.GenerateHelmRelease(helmRelease)
 	if err != nil {
-		return fmt.Errorf("failed to generate FluxCD HelmRelease: %w", err)
+		return nil, fmt.Errorf("failed to generate FluxCD HelmRelease: %w", err)
 	}
 
-	// Convert FluxCD HelmRelease to KubeObject
-	helmReleaseBytes, err := yaml.Marshal(fluxHelmRelease)
-	if err != nil {
-		return fmt.Errorf("failed to marshal FluxCD HelmRelease: %w", err)
-	}
+	// Reference an existing HelmRepository instead of generating one
+	if !provider.UsesHelmRepository(helmRelease) {
+		DebugLog("Generated FluxCD HelmRelease")
 
-	helmReleaseObj, err := fn.ParseKubeObject(helmReleaseBytes)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal FluxCD HelmRelease to KubeObject: %w", err)
+		return toKubeObjects(fluxHelmRelease)
 	}
 
 	// Generate FluxCD HelmRepository
 	helmRepo, err := provider.GenerateHelmRepository(helmRelease)
 	if err != nil {
-		return fmt.Errorf("failed to generate FluxCD HelmRepository: %w", err)
+		return nil, fmt.Errorf("failed to generate FluxCD HelmRepository: %w", err)
 	}
 
-	// Convert FluxCD HelmRepository to KubeObject
-	helmRepoBytes, err := yaml.Marshal(helmRepo)
-	if err != nil {
-		return fmt.Errorf("failed to marshal FluxCD HelmRepository: %w", err)
-	}
-
-	helmRepoObj, err := fn.ParseKubeObject(helmRepoBytes)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal FluxCD HelmRepository to KubeObject: %w", err)
-	}
-
-	// Add both FluxCD resources to the output items
-	rl.Items = append(rl.Items, helmReleaseObj, helmRepoObj)
+	DebugLog("Generated FluxCD HelmRelease and HelmRepository")
 
-	DebugLog("Added FluxCD HelmRelease and HelmRepository to output")
-
-	return nil
+	return toKubeObjects(fluxHelmRelease, helmRepo)
 }
 
 // processCrossplaneProvider handles Crossplane provider processing
-func processCrossplaneProvider(rl *fn.ResourceList, helmRelease *types.HelmRelease) error {
+func processCrossplaneProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
 	// Create Crossplane provider
 	provider := crossplane.NewCrossplaneProvider()
 
 	// Generate Crossplane Release
 	release, err := provider.GenerateRelease(helmRelease)
 	if err != nil {
-		return fmt.Errorf("failed to generate Crossplane release: %w", err)
+		return nil, fmt.Errorf("failed to generate Crossplane release: %w", err)
 	}
 
-	// Convert to KubeObject
-	releaseBytes, err := yaml.Marshal(release)
-	if err != nil {
-		return fmt.Errorf("failed to marshal Crossplane release: %w", err)
+	DebugLog("Generated Crossplane Release")
+
+	if !provider.UsesProviderConfig(helmRelease) {
+		return toKubeObjects(release)
 	}
 
-	releaseObj, err := fn.ParseKubeObject(releaseBytes)
+	// Generate the ProviderConfig the Release connects to its cluster with
+	providerConfig, err := provider.GenerateProviderConfig(helmRelease)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal to KubeObject: %w", err)
+		return nil, fmt.Errorf("failed to generate Crossplane provider config: %w", err)
 	}
 
-	// Add the Crossplane Release to the output items
-	rl.Items = append(rl.Items, releaseObj)
+	DebugLog("Generated Crossplane ProviderConfig")
 
-	DebugLog("Added Crossplane Release to output")
-
-	return nil
+	return toKubeObjects(release, providerConfig)
 }
 
 // processRancherProvider handles Rancher provider processing
-func processRancherProvider(rl *fn.ResourceList, helmRelease *types.HelmRelease) error {
+func processRancherProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
 	// Create Rancher provider
 	provider := rancher.NewRancherProvider()
 
+	// Customize a chart packaged with K3s or RKE2 instead of installing one
+	if provider.UsesHelmChartConfig(helmRelease) {
+		helmChartConfig, err := provider.GenerateHelmChartConfig(helmRelease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Rancher HelmChartConfig: %w", err)
+		}
+
+		DebugLog("Generated Rancher HelmChartConfig")
+
+		return toKubeObjects(helmChartConfig)
+	}
+
 	// Generate Rancher HelmChart
 	helmChart, err := provider.GenerateHelmChart(helmRelease)
 	if err != nil {
-		return fmt.Errorf("failed to generate Rancher HelmChart: %w", err)
+		return nil, fmt.Errorf("failed to generate Rancher HelmChart: %w", err)
 	}
 
-	// Convert to KubeObject
-	helmChartBytes, err := yaml.Marshal(helmChart)
+	DebugLog("Generated Rancher HelmChart")
+
+	return toKubeObjects(helmChart)
+}
+
+// processKappProvider handles kapp provider processing
+func processKappProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
+	// Create kapp provider
+	provider := kapp.NewKappProvider()
+
+	// Generate kapp-controller App
+	app, err := provider.GenerateApp(helmRelease)
 	if err != nil {
-		return fmt.Errorf("failed to marshal Rancher HelmChart: %w", err)
+		return nil, fmt.Errorf("failed to generate kapp App: %w", err)
 	}
 
-	helmChartObj, err := fn.ParseKubeObject(helmChartBytes)
+	DebugLog("Generated kapp App")
+
+	usesValuesSecret, err := provider.UsesValuesSecret(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+	if !usesValuesSecret {
+		return toKubeObjects(app)
+	}
+
+	// Generate the Secret holding the inline values the App reads
+	valuesSecret, err := provider.GenerateValuesSecret(helmRelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate kapp values secret: %w", err)
+	}
+
+	DebugLog("Generated kapp values Secret")
+
+	return toKubeObjects(app, valuesSecret)
+}
+
+// processFleetProvider handles Fleet provider processing
+func processFleetProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
+	// Create Fleet provider
+	provider := fleet.NewFleetProvider()
+
+	// Generate a fleet.yaml for a Fleet GitRepo instead of a Bundle
+	if provider.UsesFleetYAML(helmRelease) {
+		configMap, err := provider.GenerateFleetYAML(helmRelease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Fleet fleet.yaml: %w", err)
+		}
+
+		DebugLog("Generated Fleet fleet.yaml")
+
+		return toKubeObjects(configMap)
+	}
+
+	// Generate Fleet Bundle
+	bundle, err := provider.GenerateBundle(helmRelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Fleet Bundle: %w", err)
+	}
+
+	DebugLog("Generated Fleet Bundle")
+
+	return toKubeObjects(bundle)
+}
+
+// processCAPIProvider handles Cluster API provider processing
+func processCAPIProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
+	// Create Cluster API provider
+	provider := capi.NewCAPIProvider()
+
+	// Generate Cluster API HelmChartProxy
+	proxy, err := provider.GenerateHelmChartProxy(helmRelease)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal to KubeObject: %w", err)
+		return nil, fmt.Errorf("failed to generate Cluster API HelmChartProxy: %w", err)
 	}
 
-	// Add the Rancher HelmChart to the output items
-	rl.Items = append(rl.Items, helmChartObj)
+	DebugLog("Generated Cluster API HelmChartProxy")
+
+	return toKubeObjects(proxy)
+}
+
+// processSveltosProvider handles Sveltos provider processing
+func processSveltosProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
+	// Create Sveltos provider
+	provider := sveltos.NewSveltosProvider()
+
+	// Generate Sveltos ClusterProfile or Profile
+	profile, err := provider.GenerateProfile(helmRelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Sveltos profile: %w", err)
+	}
+
+	DebugLog("Generated Sveltos %s", profile.Kind)
+
+	return toKubeObjects(profile)
+}
+
+// processOCMProvider handles Open Cluster Management provider processing
+func processOCMProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
+	// Create Open Cluster Management provider
+	provider := ocm.NewOCMProvider()
+
+	// Generate the Subscription deploying the chart
+	subscription, err := provider.GenerateSubscription(helmRelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OCM Subscription: %w", err)
+	}
+
+	// Generate the Channel of the chart repository
+	channel, err := provider.GenerateChannel(helmRelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OCM Channel: %w", err)
+	}
+
+	DebugLog("Generated OCM Subscription and Channel")
+
+	// Reference an existing Placement instead of generating one
+	if !provider.UsesPlacement(helmRelease) {
+		return toKubeObjects(subscription, channel)
+	}
+
+	placement, err := provider.GeneratePlacement(helmRelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OCM Placement: %w", err)
+	}
+
+	DebugLog("Generated OCM Placement")
+
+	return toKubeObjects(subscription, channel, placement)
+}
+
+// processHelmfileProvider handles helmfile provider processing
+func processHelmfileProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
+	// Create helmfile provider
+	provider := helmfile.NewHelmfileProvider()
+
+	// Generate the helmfile.yaml of the release
+	releaseHelmfile, err := provider.GenerateHelmfile(helmRelease)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate helmfile.yaml: %w", err)
+	}
+
+	helmfileObj, err := helmfileObject(helmRelease, releaseHelmfile)
+	if err != nil {
+		return nil, err
+	}
+
+	DebugLog("Generated helmfile.yaml")
+
+	return []*fn.KubeObject{helmfileObj}, nil
+}
+
+// processKustomizeProvider handles kustomize provider processing
+func processKustomizeProvider(helmRelease *types.HelmRelease) ([]*fn.KubeObject, error) {
+	// Create kustomize provider
+	provider := kustomize.NewKustomizeProvider()
+
+	// Generate the HelmChartInflationGenerator or the helmCharts fragment
+	var config interface{}
+	var err error
+	if provider.UsesHelmCharts(helmRelease) {
+		config, err = provider.GenerateKustomization(helmRelease)
+	} else {
+		config, err = provider.GenerateGenerator(helmRelease)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate kustomize configuration: %w", err)
+	}
+
+	objs, err := toKubeObjects(config)
+	if err != nil {
+		return nil, err
+	}
 
-	DebugLog("Added Rancher HelmChart to output")
+	DebugLog("Generated kustomize %s", objs[0].GetKind())
 
-	return nil
+	return objs, nil
 }
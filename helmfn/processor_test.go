@@ -2,6 +2,8 @@ package helmfn
 
 import (
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/kptdev/krm-functions-sdk/go/fn"
@@ -81,10 +83,10 @@ func TestProcessFluxCDExample(t *testing.T) {
 	var generatedRelease *fn.KubeObject
 	var generatedRepo *fn.KubeObject
 	for _, item := range rl.Items {
-		if item.GetKind() == "HelmRelease" && item.GetAPIVersion() == "helm.toolkit.fluxcd.io/v2beta1" {
+		if item.GetKind() == "HelmRelease" && item.GetAPIVersion() == "helm.toolkit.fluxcd.io/v2" {
 			generatedRelease = item
 		}
-		if item.GetKind() == "HelmRepository" && item.GetAPIVersion() == "source.toolkit.fluxcd.io/v1beta1" {
+		if item.GetKind() == "HelmRepository" && item.GetAPIVersion() == "source.toolkit.fluxcd.io/v1" {
 			generatedRepo = item
 		}
 	}
@@ -107,8 +109,8 @@ func TestProcessFluxCDExample(t *testing.T) {
 	}
 
 	// Verify the generated repository has correct basic properties
-	if generatedRepo.GetName() != "my-app" {
-		t.Errorf("Expected repository name 'my-app', got '%s'", generatedRepo.GetName())
+	if generatedRepo.GetName() != "helm-github-io-examples" {
+		t.Errorf("Expected repository name 'helm-github-io-examples', got '%s'", generatedRepo.GetName())
 	}
 
 	if generatedRepo.GetNamespace() != "my-system" {
@@ -207,3 +209,553 @@ func TestProcessRancherExample(t *testing.T) {
 		t.Errorf("Expected chart namespace 'my-system', got '%s'", generatedChart.GetNamespace())
 	}
 }
+
+// TestProcessMultipleProviders tests that one HelmRelease generates resources for several providers in a stable order
+func TestProcessMultipleProviders(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Replace the single provider with an unsorted list containing a duplicate
+	if _, err := example.Release.RemoveNestedField("spec", "provider"); err != nil {
+		t.Fatalf("Failed to remove provider: %v", err)
+	}
+	if err := example.Release.SetNestedStringSlice([]string{"rancher", "fluxcd", "argocd", "fluxcd"}, "spec", "providers"); err != nil {
+		t.Fatalf("Failed to set providers: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + Application + HelmRelease + HelmRepository + HelmChart
+	expected := []struct {
+		kind     string
+		provider string
+		path     string
+	}{
+		{"ConfigMap", "", ""},
+		{"Application", "argocd", "my-app/argocd/application_my-app.yaml"},
+		{"HelmRelease", "fluxcd", "my-app/fluxcd/helmrelease_my-app.yaml"},
+		{"HelmRepository", "fluxcd", "my-app/fluxcd/helmrepository_helm-github-io-examples.yaml"},
+		{"HelmChart", "rancher", "my-app/rancher/helmchart_my-app.yaml"},
+	}
+
+	if len(rl.Items) != len(expected) {
+		t.Fatalf("Expected %d items in output, got %d", len(expected), len(rl.Items))
+	}
+
+	for i, item := range rl.Items {
+		if item.GetKind() != expected[i].kind {
+			t.Errorf("Expected item %d to be %s, got %s", i, expected[i].kind, item.GetKind())
+		}
+		if item.GetAnnotation(OriginProviderAnnotation) != expected[i].provider {
+			t.Errorf("Expected item %d provider '%s', got '%s'", i, expected[i].provider, item.GetAnnotation(OriginProviderAnnotation))
+		}
+		if item.GetAnnotation(fn.PathAnnotation) != expected[i].path {
+			t.Errorf("Expected item %d path '%s', got '%s'", i, expected[i].path, item.GetAnnotation(fn.PathAnnotation))
+		}
+	}
+
+	// Setting both fields is rejected
+	if err := rl.FunctionConfig.SetNestedString("argocd", "spec", "provider"); err != nil {
+		t.Fatalf("Failed to set provider: %v", err)
+	}
+	if _, err := Process(rl); err == nil {
+		t.Error("Expected an error when both spec.provider and spec.providers are set")
+	}
+}
+
+// TestProcessArgoCDApplicationSetExample tests the full processor pipeline using the argocd-applicationset example
+func TestProcessArgoCDApplicationSetExample(t *testing.T) {
+	// Load the argocd-applicationset example files
+	exampleDir := filepath.Join("..", "examples", "argocd-applicationset")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + generated ApplicationSet, no Application
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output (ConfigMap + ApplicationSet), got %d", len(rl.Items))
+	}
+
+	generatedAppSet := rl.Items[1]
+	if generatedAppSet.GetKind() != "ApplicationSet" || generatedAppSet.GetAPIVersion() != "argoproj.io/v1alpha1" {
+		t.Fatalf("Expected an ArgoCD ApplicationSet, got %s", generatedAppSet.ShortString())
+	}
+
+	if generatedAppSet.GetName() != "my-app" {
+		t.Errorf("Expected application set name 'my-app', got '%s'", generatedAppSet.GetName())
+	}
+}
+
+// TestProcessArgoCDAppProject tests that the AppProject is generated next to the Application
+func TestProcessArgoCDAppProject(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	if err := example.Release.SetNestedField(map[string]interface{}{"installsCRDs": true}, "spec", "argocd", "appProject"); err != nil {
+		t.Fatalf("Failed to set appProject: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + Application + AppProject
+	if len(rl.Items) != 3 {
+		t.Fatalf("Expected 3 items in output, got %d", len(rl.Items))
+	}
+
+	app, appProject := rl.Items[1], rl.Items[2]
+	if app.GetKind() != "Application" || appProject.GetKind() != "AppProject" {
+		t.Fatalf("Expected an Application and an AppProject, got %s and %s", app.ShortString(), appProject.ShortString())
+	}
+
+	if project, _, _ := app.NestedString("spec", "project"); project != appProject.GetName() {
+		t.Errorf("Expected the Application to use project '%s', got '%s'", appProject.GetName(), project)
+	}
+
+	if appProject.GetAnnotation(OriginProviderAnnotation) != "argocd" {
+		t.Errorf("Expected the AppProject to carry the origin provider annotation")
+	}
+}
+
+// TestProcessArgoCDOCIRepository tests that the Application, the AppProject and the repository Secret
+// reference an OCI chart repository by the same URL
+func TestProcessArgoCDOCIRepository(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	if err := example.Release.SetNestedString("oci://ghcr.io/examples/charts", "spec", "chart", "repo"); err != nil {
+		t.Fatalf("Failed to set chart repo: %v", err)
+	}
+	if err := example.Release.SetNestedField(map[string]interface{}{"secretName": "repo-auth"}, "spec", "chart", "auth"); err != nil {
+		t.Fatalf("Failed to set auth: %v", err)
+	}
+	if err := example.Release.SetNestedField(map[string]interface{}{"installsCRDs": true}, "spec", "argocd", "appProject"); err != nil {
+		t.Fatalf("Failed to set appProject: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	var app, appProject, repoSecret *fn.KubeObject
+	for _, item := range rl.Items {
+		switch {
+		case item.GetKind() == "Application":
+			app = item
+		case item.GetKind() == "AppProject":
+			appProject = item
+		case item.GetLabel("argocd.argoproj.io/secret-type") == "repository":
+			repoSecret = item
+		}
+	}
+	if app == nil || appProject == nil || repoSecret == nil {
+		t.Fatalf("Expected an Application, an AppProject and a repository Secret, got %v", rl.Items)
+	}
+
+	expected := "ghcr.io/examples/charts"
+	if url, _, _ := repoSecret.NestedString("stringData", "url"); url != expected {
+		t.Errorf("Expected Secret url '%s', got '%s'", expected, url)
+	}
+	if enableOCI, _, _ := repoSecret.NestedString("stringData", "enableOCI"); enableOCI != "true" {
+		t.Errorf("Expected Secret enableOCI 'true', got '%s'", enableOCI)
+	}
+	if repoURL, _, _ := app.NestedString("spec", "source", "repoURL"); repoURL != expected {
+		t.Errorf("Expected Application repoURL '%s', got '%s'", expected, repoURL)
+	}
+	if sourceRepos, _, _ := appProject.NestedStringSlice("spec", "sourceRepos"); len(sourceRepos) == 0 || sourceRepos[0] != expected {
+		t.Errorf("Expected AppProject sourceRepos to start with '%s', got %v", expected, sourceRepos)
+	}
+}
+
+// TestProcessCrossplaneProviderConfig tests that the ProviderConfig is generated next to the Release
+func TestProcessCrossplaneProviderConfig(t *testing.T) {
+	// Load the crossplane example files
+	exampleDir := filepath.Join("..", "examples", "crossplane")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	providerConfig := map[string]interface{}{"source": "InjectedIdentity"}
+	if err := example.Release.SetNestedField(providerConfig, "spec", "crossplane", "providerConfig"); err != nil {
+		t.Fatalf("Failed to set providerConfig: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + Release + ProviderConfig
+	if len(rl.Items) != 3 {
+		t.Fatalf("Expected 3 items in output, got %d", len(rl.Items))
+	}
+
+	release, config := rl.Items[1], rl.Items[2]
+	if release.GetKind() != "Release" || config.GetKind() != "ProviderConfig" {
+		t.Fatalf("Expected a Release and a ProviderConfig, got %s and %s", release.ShortString(), config.ShortString())
+	}
+
+	if name, _, _ := release.NestedString("spec", "providerConfigRef", "name"); name != config.GetName() {
+		t.Errorf("Expected the Release to use ProviderConfig '%s', got '%s'", config.GetName(), name)
+	}
+}
+
+// TestProcessSetValues tests the ArgoCD parameters of set values and the warning for values it cannot read
+func TestProcessSetValues(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	set := []interface{}{
+		map[string]interface{}{"name": "image.tag", "value": "1.2.3"},
+		map[string]interface{}{"name": "auth.password", "valueFrom": map[string]interface{}{
+			"secretKeyRef": map[string]interface{}{"name": "db", "key": "password"},
+		}},
+	}
+	if err := example.Release.SetNestedField(set, "spec", "set"); err != nil {
+		t.Fatalf("Failed to set values: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	parameters, _, err := rl.Items[1].NestedSlice("spec", "source", "helm", "parameters")
+	if err != nil || len(parameters) != 1 {
+		t.Fatalf("Expected a single helm parameter, got %v (%v)", parameters, err)
+	}
+	if name, _, _ := parameters[0].NestedString("name"); name != "image.tag" {
+		t.Errorf("Expected parameter 'image.tag', got '%s'", name)
+	}
+
+	if len(rl.Results) != 1 || !strings.Contains(rl.Results[0].Message, "spec.set") {
+		t.Errorf("Expected a warning about spec.set, got %v", rl.Results)
+	}
+}
+
+// TestProcessRancherHelmChartConfigExample tests the full processor pipeline using the rancher-helmchartconfig example
+func TestProcessRancherHelmChartConfigExample(t *testing.T) {
+	// Load the rancher-helmchartconfig example files
+	exampleDir := filepath.Join("..", "examples", "rancher-helmchartconfig")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + generated HelmChartConfig, no HelmChart
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output (ConfigMap + HelmChartConfig), got %d", len(rl.Items))
+	}
+
+	generated := rl.Items[1]
+	if generated.GetKind() != "HelmChartConfig" || generated.GetAPIVersion() != "helm.cattle.io/v1" {
+		t.Fatalf("Expected a Rancher HelmChartConfig, got %s", generated.ShortString())
+	}
+
+	if generated.GetName() != "traefik" || generated.GetNamespace() != "kube-system" {
+		t.Errorf("Expected kube-system/traefik, got %s/%s", generated.GetNamespace(), generated.GetName())
+	}
+}
+
+// TestProcessRancherHelmChartConfigWarnings tests the warnings for the fields a HelmChartConfig ignores
+func TestProcessRancherHelmChartConfigWarnings(t *testing.T) {
+	// Load the rancher-helmchartconfig example files
+	exampleDir := filepath.Join("..", "examples", "rancher-helmchartconfig")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	if err := example.Release.SetNestedString("10m", "spec", "lifecycle", "timeout"); err != nil {
+		t.Fatalf("Failed to set lifecycle: %v", err)
+	}
+	if err := example.Release.SetNestedString("registry-auth", "spec", "chart", "auth", "secretName"); err != nil {
+		t.Fatalf("Failed to set chart auth: %v", err)
+	}
+	if err := example.Release.SetNestedString("rancher/klipper-helm", "spec", "rancher", "jobImage"); err != nil {
+		t.Fatalf("Failed to set jobImage: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	var messages []string
+	for _, result := range rl.Results {
+		messages = append(messages, result.Message)
+	}
+	for _, field := range []string{"spec.lifecycle.timeout", "spec.chart.auth.secretName", "spec.rancher.jobImage"} {
+		if !slices.ContainsFunc(messages, func(message string) bool { return strings.Contains(message, field) }) {
+			t.Errorf("Expected a warning about %s, got %v", field, messages)
+		}
+	}
+	if len(messages) != 3 {
+		t.Errorf("Expected 3 warnings, got %v", messages)
+	}
+}
+
+// TestProcessKappExample tests that the kapp example generates an App and the Secret holding its values
+func TestProcessKappExample(t *testing.T) {
+	// Load the kapp example files
+	exampleDir := filepath.Join("..", "examples", "kapp")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + App + values Secret
+	if len(rl.Items) != 3 {
+		t.Fatalf("Expected 3 items in output, got %d", len(rl.Items))
+	}
+
+	for i, expected := range example.Expected {
+		generated := rl.Items[i+1]
+		if generated.GetAPIVersion() != expected.GetAPIVersion() || generated.GetKind() != expected.GetKind() ||
+			generated.GetNamespace() != expected.GetNamespace() || generated.GetName() != expected.GetName() {
+			t.Errorf("Expected %s, got %s", expected.ShortString(), generated.ShortString())
+		}
+	}
+
+	templates, _, _ := rl.Items[1].NestedSlice("spec", "template")
+	if len(templates) != 1 {
+		t.Fatalf("Expected a single template step, got %d", len(templates))
+	}
+	valuesFrom, _, _ := templates[0].NestedSlice("helmTemplate", "valuesFrom")
+	if len(valuesFrom) != 2 {
+		t.Fatalf("Expected 2 valuesFrom entries, got %d", len(valuesFrom))
+	}
+	if secretName, _, _ := valuesFrom[1].NestedString("secretRef", "name"); secretName != rl.Items[2].GetName() {
+		t.Errorf("Expected the App to read the values Secret '%s', got '%s'", rl.Items[2].GetName(), secretName)
+	}
+}
+
+// TestProcessFleetExample tests that the fleet example generates a Bundle with a target per destination
+func TestProcessFleetExample(t *testing.T) {
+	// Load the fleet example files
+	exampleDir := filepath.Join("..", "examples", "fleet")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + Bundle
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output, got %d", len(rl.Items))
+	}
+
+	bundle, expected := rl.Items[1], example.Expected[0]
+	if bundle.GetAPIVersion() != expected.GetAPIVersion() || bundle.GetKind() != expected.GetKind() ||
+		bundle.GetNamespace() != expected.GetNamespace() || bundle.GetName() != expected.GetName() {
+		t.Errorf("Expected %s, got %s", expected.ShortString(), bundle.ShortString())
+	}
+
+	targets, _, _ := bundle.NestedSlice("spec", "targets")
+	if len(targets) != 2 {
+		t.Fatalf("Expected 2 targets, got %d", len(targets))
+	}
+
+	if replicas, _, _ := targets[1].NestedInt("helm", "values", "replicaCount"); replicas != 3 {
+		t.Errorf("Expected the production target to run 3 replicas, got %d", replicas)
+	}
+}
+
+// TestProcessCAPIExample tests that the capi example generates a HelmChartProxy next to the Clusters
+func TestProcessCAPIExample(t *testing.T) {
+	// Load the capi example files
+	exampleDir := filepath.Join("..", "examples", "capi")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + HelmChartProxy
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output, got %d", len(rl.Items))
+	}
+
+	proxy, expected := rl.Items[1], example.Expected[0]
+	if proxy.GetAPIVersion() != expected.GetAPIVersion() || proxy.GetKind() != expected.GetKind() ||
+		proxy.GetNamespace() != expected.GetNamespace() || proxy.GetName() != expected.GetName() {
+		t.Errorf("Expected %s, got %s", expected.ShortString(), proxy.ShortString())
+	}
+
+	expectedTemplate, _, _ := expected.NestedString("spec", "valuesTemplate")
+	if valuesTemplate, _, _ := proxy.NestedString("spec", "valuesTemplate"); valuesTemplate != expectedTemplate {
+		t.Errorf("Expected values template %q, got %q", expectedTemplate, valuesTemplate)
+	}
+}
+
+// TestProcessSveltosExample tests that the sveltos example generates a cluster-scoped ClusterProfile
+func TestProcessSveltosExample(t *testing.T) {
+	// Load the sveltos example files
+	exampleDir := filepath.Join("..", "examples", "sveltos")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + ClusterProfile
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output, got %d", len(rl.Items))
+	}
+
+	profile, expected := rl.Items[1], example.Expected[0]
+	if profile.GetAPIVersion() != expected.GetAPIVersion() || profile.GetKind() != expected.GetKind() ||
+		profile.GetNamespace() != expected.GetNamespace() || profile.GetName() != expected.GetName() {
+		t.Errorf("Expected %s, got %s", expected.ShortString(), profile.ShortString())
+	}
+
+	if len(rl.Results) != 0 {
+		t.Errorf("Expected no warnings, got %v", rl.Results)
+	}
+}
+
+// TestProcessOCMExample tests that the ocm example generates a Subscription, its Channel and its Placement
+func TestProcessOCMExample(t *testing.T) {
+	// Load the ocm example files
+	exampleDir := filepath.Join("..", "examples", "ocm")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + Subscription + Channel + Placement
+	if len(rl.Items) != 4 {
+		t.Fatalf("Expected 4 items in output, got %d", len(rl.Items))
+	}
+
+	for i, expected := range example.Expected {
+		generated := rl.Items[i+1]
+		if generated.GetAPIVersion() != expected.GetAPIVersion() || generated.GetKind() != expected.GetKind() ||
+			generated.GetNamespace() != expected.GetNamespace() || generated.GetName() != expected.GetName() {
+			t.Errorf("Expected %s, got %s", expected.ShortString(), generated.ShortString())
+		}
+	}
+}
+
+// TestProcessHelmfileExample tests that the helmfile example generates a ConfigMap holding the helmfile.yaml
+func TestProcessHelmfileExample(t *testing.T) {
+	// Load the helmfile example files
+	exampleDir := filepath.Join("..", "examples", "helmfile")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + helmfile ConfigMap
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output, got %d", len(rl.Items))
+	}
+
+	generated := rl.Items[1]
+	expected := example.Expected[0]
+	if generated.GetKind() != expected.GetKind() || generated.GetNamespace() != expected.GetNamespace() || generated.GetName() != expected.GetName() {
+		t.Errorf("Expected %s, got %s", expected.ShortString(), generated.ShortString())
+	}
+
+	data, _, _ := generated.NestedString("data", "helmfile.yaml")
+	expectedData, _, _ := expected.NestedString("data", "helmfile.yaml")
+	// The example loader trims the trailing newline of the last document
+	if strings.TrimSpace(data) != strings.TrimSpace(expectedData) {
+		t.Errorf("Expected helmfile.yaml:\n%s\ngot:\n%s", expectedData, data)
+	}
+}
+
+// TestProcessKustomizeExample tests that the kustomize example generates a HelmChartInflationGenerator
+func TestProcessKustomizeExample(t *testing.T) {
+	// Load the kustomize example files
+	exampleDir := filepath.Join("..", "examples", "kustomize")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	// Original ConfigMap + HelmChartInflationGenerator
+	if len(rl.Items) != 2 {
+		t.Fatalf("Expected 2 items in output, got %d", len(rl.Items))
+	}
+
+	generated := rl.Items[1]
+	expected := example.Expected[0]
+	if generated.GetAPIVersion() != expected.GetAPIVersion() || generated.GetKind() != expected.GetKind() || generated.GetName() != expected.GetName() {
+		t.Errorf("Expected %s, got %s", expected.ShortString(), generated.ShortString())
+	}
+
+	for _, field := range []string{"releaseName", "namespace", "valuesFile"} {
+		value, _, _ := generated.NestedString(field)
+		expectedValue, _, _ := expected.NestedString(field)
+		if value != expectedValue {
+			t.Errorf("Expected %s '%s', got '%s'", field, expectedValue, value)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package helmfn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"sigs.k8s.io/yaml"
+)
+
+// rancherChartConfig is the support key of the rancher provider customizing a packaged chart through a
+// HelmChartConfig, which only carries the values and the failure policy. It supports no lifecycle or chart
+// auth field.
+const rancherChartConfig = "rancher/chartConfig"
+
+// lifecycleSupport lists the spec.lifecycle fields each provider maps. A field is supported when it or
+// one of its parents is listed, every field of a provider missing from the list is ignored.
+var lifecycleSupport = map[string][]string{
+	"argocd":     {"suspend", "maxHistory"},
+	"capi":       {"timeout", "maxHistory"},
+	"crossplane": {"upgrade.retries", "timeout", "suspend"},
+	"fleet":      {"timeout", "suspend", "maxHistory"},
+	"fluxcd":     {"install", "upgrade", "rollback", "test", "driftDetection", "timeout", "suspend", "maxHistory"},
+	"helmfile":   {"timeout"},
+	"kapp":       {"suspend"},
+	"rancher":    {"install.retries", "upgrade.retries", "timeout"},
+	"sveltos":    {"timeout", "maxHistory"},
+}
+
+// chartAuthSupport lists the spec.chart.auth fields each provider maps, in the same form as lifecycleSupport
+var chartAuthSupport = map[string][]string{
+	"argocd":     {"secretName", "certSecretName", "passCredentials", "insecureSkipTLSVerify"},
+	"capi":       {"insecureSkipTLSVerify"},
+	"crossplane": {"secretName", "insecureSkipTLSVerify"},
+	"fluxcd":     {"secretName", "certSecretName", "passCredentials"},
+	"helmfile":   {"passCredentials", "insecureSkipTLSVerify"},
+	"kapp":       {"secretName"},
+	"ocm":        {"insecureSkipTLSVerify"},
+	"rancher":    {"secretName", "caConfigMapName", "passCredentials", "insecureSkipTLSVerify"},
+	"sveltos":    {"secretName", "insecureSkipTLSVerify"},
+}
+
+// rancherChartConfigSupport lists the spec.rancher fields a HelmChartConfig maps, the others configure the
+// job helm-controller runs to install a HelmChart
+var rancherChartConfigSupport = []string{"chartConfig", "failurePolicy"}
+
+// supportKey returns the key of the support lists for the provider, which depends on how the HelmRelease
+// uses it, and how that usage is described in warnings
+func supportKey(helmRelease *types.HelmRelease, provider string) (string, string) {
+	if provider == "rancher" && helmRelease.Spec.Rancher != nil && helmRelease.Spec.Rancher.ChartConfig {
+		return rancherChartConfig, "the rancher provider with spec.rancher.chartConfig"
+	}
+	return provider, "the " + provider + " provider"
+}
+
+// unsupportedLifecycleFields returns the spec.lifecycle fields set on the HelmRelease which the provider
+// ignores, as sorted dotted paths such as upgrade.strategy
+func unsupportedLifecycleFields(helmRelease *types.HelmRelease, provider string) ([]string, error) {
+	if helmRelease.Spec.Lifecycle == nil {
+		return nil, nil
+	}
+	key, _ := supportKey(helmRelease, provider)
+	return unsupportedFields(helmRelease.Spec.Lifecycle, lifecycleSupport[key])
+}
+
+// unsupportedChartAuthFields returns the spec.chart.auth fields set on the HelmRelease which the provider
+// ignores, as sorted dotted paths such as caConfigMapName
+func unsupportedChartAuthFields(helmRelease *types.HelmRelease, provider string) ([]string, error) {
+	if helmRelease.Spec.Chart.Auth == nil {
+		return nil, nil
+	}
+	key, _ := supportKey(helmRelease, provider)
+	return unsupportedFields(helmRelease.Spec.Chart.Auth, chartAuthSupport[key])
+}
+
+// unsupportedRancherFields returns the spec.rancher fields set on the HelmRelease which a HelmChartConfig
+// ignores, as sorted dotted paths such as jobImage
+func unsupportedRancherFields(helmRelease *types.HelmRelease, provider string) ([]string, error) {
+	if key, _ := supportKey(helmRelease, provider); key != rancherChartConfig {
+		return nil, nil
+	}
+	return unsupportedFields(helmRelease.Spec.Rancher, rancherChartConfigSupport)
+}
+
+// unsupportedFields returns the dotted paths of the fields set on spec which are not in the supported list
+func unsupportedFields(spec interface{}, supported []string) ([]string, error) {
+	specBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec: %w", err)
+	}
+
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(specBytes, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec: %w", err)
+	}
+
+	var unsupported []string
+	for _, field := range fieldPaths(fields, "") {
+		if !isSupported(field, supported) {
+			unsupported = append(unsupported, field)
+		}
+	}
+	return unsupported, nil
+}
+
+// fieldPaths returns the dotted paths of the leaves of a map in sorted order, lists are leaves
+func fieldPaths(m map[string]interface{}, prefix string) []string {
+	var paths []string
+	for _, k := range sortedKeys(m) {
+		if nested, ok := m[k].(map[string]interface{}); ok && len(nested) > 0 {
+			paths = append(paths, fieldPaths(nested, prefix+k+".")...)
+			continue
+		}
+		paths = append(paths, prefix+k)
+	}
+	return paths
+}
+
+// isSupported reports whether the field or one of its parents is in the supported list
+func isSupported(field string, supported []string) bool {
+	for _, s := range supported {
+		if field == s || strings.HasPrefix(field, s+".") {
+			return true
+		}
+	}
+	return false
+}
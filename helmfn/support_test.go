@@ -0,0 +1,106 @@
+package helmfn
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+func TestUnsupportedLifecycleFields(t *testing.T) {
+	maxHistory := 5
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{Lifecycle: &types.LifecycleSpec{
+		Upgrade:    &types.RemediationSpec{Retries: 3, Strategy: types.RemediationUninstall},
+		Test:       &types.TestSpec{Enable: true},
+		Timeout:    "10m",
+		MaxHistory: &maxHistory,
+	}}}
+
+	tests := []struct {
+		provider string
+		expected []string
+	}{
+		{"fluxcd", nil},
+		{"argocd", []string{"test.enable", "timeout", "upgrade.retries", "upgrade.strategy"}},
+		{"crossplane", []string{"maxHistory", "test.enable", "upgrade.strategy"}},
+		{"rancher", []string{"maxHistory", "test.enable", "upgrade.strategy"}},
+		{"unknown", []string{"maxHistory", "test.enable", "timeout", "upgrade.retries", "upgrade.strategy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			unsupported, err := unsupportedLifecycleFields(helmRelease, tt.provider)
+			if err != nil {
+				t.Fatalf("unsupportedLifecycleFields failed: %v", err)
+			}
+			if !reflect.DeepEqual(unsupported, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, unsupported)
+			}
+		})
+	}
+}
+
+func TestUnsupportedChartAuthFields(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{Chart: types.ChartSpec{Auth: &types.ChartAuthSpec{
+		SecretName:            "repo-auth",
+		CertSecretName:        "repo-tls",
+		CAConfigMapName:       "repo-ca",
+		InsecureSkipTLSVerify: true,
+	}}}}
+
+	tests := []struct {
+		provider string
+		expected []string
+	}{
+		{"argocd", []string{"caConfigMapName"}},
+		{"crossplane", []string{"caConfigMapName", "certSecretName"}},
+		{"fluxcd", []string{"caConfigMapName", "insecureSkipTLSVerify"}},
+		{"rancher", []string{"certSecretName"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			unsupported, err := unsupportedChartAuthFields(helmRelease, tt.provider)
+			if err != nil {
+				t.Fatalf("unsupportedChartAuthFields failed: %v", err)
+			}
+			if !reflect.DeepEqual(unsupported, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, unsupported)
+			}
+		})
+	}
+}
+
+// TestProcessWarnsAboutUnsupportedLifecycle tests that ignored lifecycle settings are reported as warnings
+func TestProcessWarnsAboutUnsupportedLifecycle(t *testing.T) {
+	// Load the rancher example files
+	exampleDir := filepath.Join("..", "examples", "rancher")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	lifecycle := map[string]interface{}{
+		"timeout": "10m",
+		"test":    map[string]interface{}{"enable": true},
+	}
+	if err := example.Release.SetNestedField(lifecycle, "spec", "lifecycle"); err != nil {
+		t.Fatalf("Failed to set lifecycle: %v", err)
+	}
+
+	rl := example.CreateResourceList()
+	if _, err := Process(rl); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if len(rl.Results) != 1 || !strings.Contains(rl.Results[0].Message, "spec.lifecycle.test.enable") {
+		t.Fatalf("Expected a single warning about spec.lifecycle.test.enable, got %v", rl.Results)
+	}
+
+	if timeout, _, _ := rl.Items[len(rl.Items)-1].NestedString("spec", "timeout"); timeout != "10m" {
+		t.Errorf("Expected the HelmChart timeout '10m', got '%s'", timeout)
+	}
+}
@@ -1,6 +1,11 @@
 package types
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -13,9 +18,54 @@ type HelmRelease struct {
 
 // HelmReleaseSpec defines the desired state of HelmRelease
 type HelmReleaseSpec struct {
-	Provider string    `json:"provider,omitempty"`
-	Chart    ChartSpec `json:"chart,omitempty"`
-	// Values will be added in a future phase
+	Provider string `json:"provider,omitempty"`
+	// Providers generates resources for several providers at once, mutually exclusive with Provider
+	Providers []string  `json:"providers,omitempty"`
+	Chart     ChartSpec `json:"chart,omitempty"`
+	// Values are the inline values passed to the chart
+	Values map[string]interface{} `json:"values,omitempty"`
+	// ValuesFiles are values files applied in order, either from the chart itself or from a git repository
+	ValuesFiles []ValuesFile `json:"valuesFiles,omitempty"`
+	// Set overrides single values like helm --set, applied after all other values
+	Set []SetValue `json:"set,omitempty"`
+	// Sync controls how the provider reconciles the release
+	Sync *SyncSpec `json:"sync,omitempty"`
+	// Lifecycle controls how the release is installed, upgraded, tested and rolled back
+	Lifecycle *LifecycleSpec `json:"lifecycle,omitempty"`
+	// DependsOn lists the HelmReleases which must be ready before this one is installed
+	DependsOn []ReleaseReference `json:"dependsOn,omitempty"`
+	// Propagation controls which labels and annotations of the HelmRelease are copied to generated resources
+	Propagation *PropagationSpec `json:"propagation,omitempty"`
+	// Output controls the files the generated resources are written to by kpt
+	Output *OutputSpec `json:"output,omitempty"`
+	// Destinations deploys the release to several clusters, each with its own values overrides
+	Destinations []Destination `json:"destinations,omitempty"`
+	// ClusterSelector selects the clusters to deploy to by label, for providers managing many clusters
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	// ArgoCD holds settings only understood by the argocd provider
+	ArgoCD *ArgoCDSpec `json:"argocd,omitempty"`
+	// FluxCD holds settings only understood by the fluxcd provider
+	FluxCD *FluxCDSpec `json:"fluxcd,omitempty"`
+	// Crossplane holds settings only understood by the crossplane provider
+	Crossplane *CrossplaneSpec `json:"crossplane,omitempty"`
+	// Rancher holds settings only understood by the rancher provider
+	Rancher *RancherSpec `json:"rancher,omitempty"`
+	// Kapp holds settings only understood by the kapp provider
+	Kapp *KappSpec `json:"kapp,omitempty"`
+	// Fleet holds settings only understood by the fleet provider
+	Fleet *FleetSpec `json:"fleet,omitempty"`
+	// CAPI holds settings only understood by the capi provider
+	CAPI *CAPISpec `json:"capi,omitempty"`
+	// Sveltos holds settings only understood by the sveltos provider
+	Sveltos *SveltosSpec `json:"sveltos,omitempty"`
+	// OCM holds settings only understood by the ocm provider
+	OCM *OCMSpec `json:"ocm,omitempty"`
+	// Helmfile holds settings only understood by the helmfile provider
+	Helmfile *HelmfileSpec `json:"helmfile,omitempty"`
+	// Kustomize holds settings only understood by the kustomize provider
+	Kustomize *KustomizeSpec `json:"kustomize,omitempty"`
+	// ProviderOverrides holds provider specific changes applied to the generated resources, keyed by provider name
+	ProviderOverrides map[string]ProviderOverride `json:"providerOverrides,omitempty"`
 	// ValuesSelector will be added in a future phase
 }
 
@@ -24,4 +74,556 @@ type ChartSpec struct {
 	Name    string `json:"name,omitempty"`
 	Version string `json:"version,omitempty"`
 	Repo    string `json:"repo,omitempty"`
+	// Interval is how often the chart source is checked for new versions, e.g. 10m
+	Interval string `json:"interval,omitempty"`
+	// ReconcileStrategy is ChartVersion or Revision, what makes the chart be fetched again
+	ReconcileStrategy string `json:"reconcileStrategy,omitempty"`
+	// ValuesFiles are values files packaged in the chart, merged in order over its values.yaml
+	ValuesFiles []string `json:"valuesFiles,omitempty"`
+	// Verify checks the signature of charts pulled from OCI registries
+	Verify *VerifySpec `json:"verify,omitempty"`
+	// Auth holds the credentials used to pull the chart
+	Auth *ChartAuthSpec `json:"auth,omitempty"`
+}
+
+// ChartAuthSpec defines the credentials used to pull the chart, the Secrets and ConfigMaps are in the
+// namespace of the release
+type ChartAuthSpec struct {
+	// SecretName is a Secret holding the username and password used for basic auth
+	SecretName string `json:"secretName,omitempty"`
+	// CertSecretName is a Secret holding the TLS client certificate in the tls.crt and tls.key keys and
+	// optionally the CA bundle of the repository in the ca.crt key
+	CertSecretName string `json:"certSecretName,omitempty"`
+	// CAConfigMapName is a ConfigMap holding the CA bundle of the repository in the ca.crt key
+	CAConfigMapName string `json:"caConfigMapName,omitempty"`
+	// PassCredentials passes the credentials to charts served from another host than the repository
+	PassCredentials bool `json:"passCredentials,omitempty"`
+	// InsecureSkipTLSVerify skips the verification of the repository certificate
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// Chart reconcile strategies
+const (
+	// ReconcileStrategyChartVersion fetches the chart again when its version changes
+	ReconcileStrategyChartVersion = "ChartVersion"
+	// ReconcileStrategyRevision fetches the chart again when the revision of its source changes
+	ReconcileStrategyRevision = "Revision"
+)
+
+// VerifySpec defines how the signature of an OCI chart is verified
+type VerifySpec struct {
+	// Provider is cosign or notation, defaults to cosign
+	Provider string `json:"provider,omitempty"`
+	// SecretName is the Secret holding the public keys, keyless verification is used without it
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// SetValue overrides a single value like helm --set
+type SetValue struct {
+	// Name is the dotted path of the value, e.g. image.tag, dots in keys are escaped with a backslash
+	Name string `json:"name"`
+	// Value is parsed like helm --set does, so true, false, null and integers are not strings
+	Value string `json:"value,omitempty"`
+	// ValueFrom reads the value from a key of a Secret or ConfigMap in the namespace of the release
+	ValueFrom *ValueSource `json:"valueFrom,omitempty"`
+}
+
+// ValueSource selects the Secret or ConfigMap key a value is read from, only one field is set
+type ValueSource struct {
+	SecretKeyRef    *KeySelector `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *KeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// KeySelector selects a key of a Secret or ConfigMap
+type KeySelector struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Optional ignores a missing Secret, ConfigMap or key
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ValuesFile references a Helm values file
+type ValuesFile struct {
+	// Path of the values file, relative to the chart root or to the git repository root when Repo is set
+	Path string `json:"path"`
+	// Repo is the URL of the git repository holding the values file, the file ships with the chart when empty
+	Repo string `json:"repo,omitempty"`
+	// Revision is the git branch, tag or commit, defaults to HEAD
+	Revision string `json:"revision,omitempty"`
+}
+
+// SyncSpec defines portable reconciliation settings, each provider maps them to its closest equivalent
+type SyncSpec struct {
+	// Automated installs and upgrades the release without manual intervention
+	Automated bool `json:"automated,omitempty"`
+	// Prune deletes resources which are no longer part of the release
+	Prune bool `json:"prune,omitempty"`
+	// SelfHeal reverts changes made to the resources in the cluster
+	SelfHeal bool `json:"selfHeal,omitempty"`
+	// Retry retries failed syncs
+	Retry *RetrySpec `json:"retry,omitempty"`
+	// CreateNamespace creates the target namespace when missing
+	CreateNamespace bool `json:"createNamespace,omitempty"`
+	// ServerSideApply applies the resources with server side apply
+	ServerSideApply bool `json:"serverSideApply,omitempty"`
+	// IgnoreCRDDrift ignores changes made to CustomResourceDefinitions in the cluster
+	IgnoreCRDDrift bool `json:"ignoreCRDDrift,omitempty"`
+}
+
+// RetrySpec defines how failed syncs are retried
+type RetrySpec struct {
+	// Limit is the maximum number of retries
+	Limit int `json:"limit,omitempty"`
+	// Backoff is the delay between retries
+	Backoff *BackoffSpec `json:"backoff,omitempty"`
+}
+
+// BackoffSpec defines an exponential backoff
+type BackoffSpec struct {
+	// Duration is the first delay, e.g. 5s
+	Duration string `json:"duration,omitempty"`
+	// Factor multiplies the delay after each retry
+	Factor int `json:"factor,omitempty"`
+	// MaxDuration caps the delay, e.g. 3m
+	MaxDuration string `json:"maxDuration,omitempty"`
+}
+
+// LifecycleSpec defines how the Helm release is installed, upgraded, tested and rolled back. The fields
+// follow the FluxCD HelmRelease, providers without an equivalent ignore them with a warning.
+type LifecycleSpec struct {
+	// Install configures the remediation of failed installs
+	Install *RemediationSpec `json:"install,omitempty"`
+	// Upgrade configures the remediation of failed upgrades
+	Upgrade *RemediationSpec `json:"upgrade,omitempty"`
+	// Rollback configures the Helm rollback action
+	Rollback *RollbackSpec `json:"rollback,omitempty"`
+	// Test configures the Helm test action run after installs and upgrades
+	Test *TestSpec `json:"test,omitempty"`
+	// DriftDetection configures how changes made to the release resources in the cluster are handled
+	DriftDetection *DriftDetectionSpec `json:"driftDetection,omitempty"`
+	// Timeout is how long to wait for Helm actions, e.g. 10m
+	Timeout string `json:"timeout,omitempty"`
+	// Suspend stops reconciling the release
+	Suspend bool `json:"suspend,omitempty"`
+	// MaxHistory is the number of release revisions Helm keeps
+	MaxHistory *int `json:"maxHistory,omitempty"`
+}
+
+// TimeoutSeconds returns the timeout in whole seconds, for providers which do not read durations, or 0
+// when it is not set
+func (l *LifecycleSpec) TimeoutSeconds() (int, error) {
+	if l.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(l.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse timeout: %w", err)
+	}
+	return int(timeout.Seconds()), nil
+}
+
+// ReleaseReference references another HelmRelease
+type ReleaseReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the namespace of the referencing HelmRelease
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// Upgrade remediation strategies
+const (
+	// RemediationRollback rolls a failed upgrade back to the last successful release
+	RemediationRollback = "rollback"
+	// RemediationUninstall uninstalls a failed upgrade
+	RemediationUninstall = "uninstall"
+)
+
+// RemediationSpec defines what happens when a Helm install or upgrade fails
+type RemediationSpec struct {
+	// Retries is the number of retries after a failure, a negative value retries forever
+	Retries int `json:"retries,omitempty"`
+	// RemediateLastFailure also remediates the last failure once the retries are exhausted
+	RemediateLastFailure *bool `json:"remediateLastFailure,omitempty"`
+	// Strategy is rollback or uninstall, only used for upgrades
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// RollbackSpec defines how a failed upgrade is rolled back
+type RollbackSpec struct {
+	// CleanupOnFail deletes the resources created by the failed upgrade
+	CleanupOnFail bool `json:"cleanupOnFail,omitempty"`
+	// Force replaces resources which cannot be updated
+	Force bool `json:"force,omitempty"`
+	// Recreate restarts the pods of the rolled back resources
+	Recreate bool `json:"recreate,omitempty"`
+}
+
+// TestSpec defines the Helm test action
+type TestSpec struct {
+	// Enable runs the chart tests after installs and upgrades
+	Enable bool `json:"enable,omitempty"`
+	// IgnoreFailures keeps failed tests from failing the release
+	IgnoreFailures bool `json:"ignoreFailures,omitempty"`
+}
+
+// Drift detection modes
+const (
+	// DriftDetectionEnabled corrects drift in the cluster
+	DriftDetectionEnabled = "enabled"
+	// DriftDetectionWarn only reports drift in the cluster
+	DriftDetectionWarn = "warn"
+	// DriftDetectionDisabled ignores drift in the cluster
+	DriftDetectionDisabled = "disabled"
+)
+
+// DriftDetectionSpec defines how drift in the cluster is handled
+type DriftDetectionSpec struct {
+	// Mode is enabled, warn or disabled
+	Mode string `json:"mode,omitempty"`
+}
+
+// Destination is one cluster and namespace a release is deployed to
+type Destination struct {
+	// Name identifies the destination, it is the cluster name for providers which register clusters by name
+	Name string `json:"name"`
+	// Server is the API server URL of the cluster
+	Server string `json:"server,omitempty"`
+	// Namespace defaults to the namespace of the HelmRelease
+	Namespace string `json:"namespace,omitempty"`
+	// Values are deep merged over the release values for this destination only
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// ArgoCDSpec defines settings only understood by the argocd provider
+type ArgoCDSpec struct {
+	// ApplicationSet generates an ApplicationSet instead of an Application
+	ApplicationSet *ApplicationSetSpec `json:"applicationSet,omitempty"`
+	// AppProject generates an AppProject scoped to the release and deploys the release with it
+	AppProject *AppProjectSpec `json:"appProject,omitempty"`
+}
+
+// AppProjectSpec defines the AppProject generated for a release. The project only allows the chart
+// repository as source and the release namespace as destination.
+type AppProjectSpec struct {
+	// Name defaults to the release name
+	Name string `json:"name,omitempty"`
+	// InstallsCRDs allows the chart to manage CustomResourceDefinitions
+	InstallsCRDs bool `json:"installsCRDs,omitempty"`
+	// ClusterResources lists the other cluster scoped resources the chart is allowed to manage
+	ClusterResources []GroupKind `json:"clusterResources,omitempty"`
+}
+
+// GroupKind identifies a kind of resource, an empty group is the core API group
+type GroupKind struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+}
+
+// ApplicationSet generators
+const (
+	// GeneratorList generates one Application per entry of spec.destinations
+	GeneratorList = "list"
+	// GeneratorClusters generates one Application per ArgoCD cluster matching spec.clusterSelector
+	GeneratorClusters = "clusters"
+	// GeneratorMatrix generates one Application per destination on every cluster matching spec.clusterSelector
+	GeneratorMatrix = "matrix"
+)
+
+// ApplicationSetSpec defines how the ApplicationSet is generated
+type ApplicationSetSpec struct {
+	// Generator is one of list, clusters or matrix, defaults to list when destinations are set and clusters otherwise
+	Generator string `json:"generator,omitempty"`
+}
+
+// FluxCDSpec defines settings only understood by the fluxcd provider
+type FluxCDSpec struct {
+	// SourceRef references an existing HelmRepository instead of generating one
+	SourceRef *ObjectReference `json:"sourceRef,omitempty"`
+}
+
+// CrossplaneSpec defines settings only understood by the crossplane provider
+type CrossplaneSpec struct {
+	// ProviderConfigRef is the name of the ProviderConfig the Release uses, defaults to default or to the
+	// namespace and name of the release when the ProviderConfig is generated
+	ProviderConfigRef string `json:"providerConfigRef,omitempty"`
+	// ProviderConfig generates the ProviderConfig the Release uses
+	ProviderConfig *ProviderConfigSpec `json:"providerConfig,omitempty"`
+	// ConnectionDetails lists the fields of release resources published in the connection secret
+	ConnectionDetails []ConnectionDetail `json:"connectionDetails,omitempty"`
+	// WriteConnectionSecretToRef is the Secret the connection details are written to
+	WriteConnectionSecretToRef *ObjectReference `json:"writeConnectionSecretToRef,omitempty"`
+	// PatchesFrom lists the Secret and ConfigMap keys in the namespace of the release holding kustomize
+	// patches provider-helm applies to the rendered chart, the key is required
+	PatchesFrom []ValuesReference `json:"patchesFrom,omitempty"`
+}
+
+// Crossplane credentials sources
+const (
+	// CredentialsInjectedIdentity uses the identity of the provider pod, i.e. the cluster it runs in
+	CredentialsInjectedIdentity = "InjectedIdentity"
+	// CredentialsSecret uses a kubeconfig stored in a Secret
+	CredentialsSecret = "Secret"
+)
+
+// ProviderConfigSpec defines how provider-helm connects to the cluster the release is installed in
+type ProviderConfigSpec struct {
+	// Source is InjectedIdentity or Secret, defaults to InjectedIdentity
+	Source string `json:"source,omitempty"`
+	// SecretRef is the key of the Secret holding the kubeconfig, required for the Secret source
+	SecretRef *SecretKeyReference `json:"secretRef,omitempty"`
+}
+
+// SecretKeyReference references a key of a Secret
+type SecretKeyReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the namespace of the HelmRelease
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key"`
+}
+
+// ConnectionDetail publishes a field of a release resource in the connection secret
+type ConnectionDetail struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	// Namespace defaults to the namespace of the HelmRelease
+	Namespace string `json:"namespace,omitempty"`
+	// FieldPath is the field published, e.g. data.password or spec.clusterIP
+	FieldPath string `json:"fieldPath"`
+	// ToConnectionSecretKey is the key it is published under
+	ToConnectionSecretKey string `json:"toConnectionSecretKey"`
+	// SkipPartOfReleaseCheck allows resources which are not part of the release
+	SkipPartOfReleaseCheck bool `json:"skipPartOfReleaseCheck,omitempty"`
+}
+
+// RancherSpec defines settings only understood by the rancher provider
+type RancherSpec struct {
+	// ChartConfig customizes a chart packaged with K3s or RKE2, named by spec.chart.name, through a
+	// HelmChartConfig instead of installing the chart with a HelmChart
+	ChartConfig bool `json:"chartConfig,omitempty"`
+	// FailurePolicy is reinstall or abort, what the helm-controller does when an install or upgrade fails
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+	// JobImage is the image of the job running helm
+	JobImage string `json:"jobImage,omitempty"`
+	// Bootstrap installs the chart before the cluster is ready, e.g. a CNI
+	Bootstrap bool `json:"bootstrap,omitempty"`
+	// DockerRegistrySecret is a Secret with the docker config used to pull charts from OCI registries
+	DockerRegistrySecret string `json:"dockerRegistrySecret,omitempty"`
+	// PodSecurityContext is the security context of the job pod
+	PodSecurityContext map[string]interface{} `json:"podSecurityContext,omitempty"`
+	// BackOffLimit is the number of job retries, it overrides the limit derived from retries
+	BackOffLimit *int32 `json:"backOffLimit,omitempty"`
+}
+
+// KappSpec defines settings only understood by the kapp provider
+type KappSpec struct {
+	// ServiceAccountName is the service account kapp-controller deploys the release with, required
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// ValuesFrom lists the Secrets and ConfigMaps in the namespace of the release holding values files,
+	// every key is a values file applied before the inline values
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+}
+
+// ValuesReference references a Secret or ConfigMap holding values files
+type ValuesReference struct {
+	// Kind is Secret or ConfigMap
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Key is the key holding the values file for providers reading a single key, defaults to values.yaml
+	Key string `json:"key,omitempty"`
+}
+
+// FleetSpec defines settings only understood by the fleet provider
+type FleetSpec struct {
+	// Workspace is the namespace of the Bundle, defaults to fleet-local without destinations or cluster
+	// selector and fleet-default otherwise
+	Workspace string `json:"workspace,omitempty"`
+	// FleetYAML generates a ConfigMap holding a fleet.yaml for a git repository instead of a Bundle
+	FleetYAML bool `json:"fleetYAML,omitempty"`
+	// ValuesFrom lists the Secrets and ConfigMaps in the namespace of the release holding a values file
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+}
+
+// CAPISpec defines settings only understood by the capi provider
+type CAPISpec struct {
+	// Namespace is the namespace of the Cluster API Clusters the release is installed on, defaults to default
+	Namespace string `json:"namespace,omitempty"`
+	// RenderTemplates lets the add-on provider render the values as Go templates for each cluster, e.g.
+	// {{ .Cluster.metadata.name }}, otherwise they are escaped and used as they are
+	RenderTemplates bool `json:"renderTemplates,omitempty"`
+}
+
+// SveltosSpec defines settings only understood by the sveltos provider
+type SveltosSpec struct {
+	// Namespaced generates a Profile in the namespace of the release, matching only the clusters of that
+	// namespace, instead of a ClusterProfile
+	Namespaced bool `json:"namespaced,omitempty"`
+	// ValuesFrom lists the Secrets and ConfigMaps in the namespace of the release holding values files,
+	// every key is a values file
+	ValuesFrom []ValuesReference `json:"valuesFrom,omitempty"`
+	// RenderTemplates lets Sveltos render the values as Go templates for each cluster, e.g.
+	// {{ .Cluster.metadata.name }}, otherwise they are escaped and used as they are
+	RenderTemplates bool `json:"renderTemplates,omitempty"`
+}
+
+// OCMSpec defines settings only understood by the ocm provider
+type OCMSpec struct {
+	// Placement names an existing Placement in the namespace of the release, otherwise one is generated
+	// from spec.clusterSelector
+	Placement string `json:"placement,omitempty"`
+}
+
+// HelmfileSpec defines settings only understood by the helmfile provider
+type HelmfileSpec struct {
+	// Mode is configMap or file, defaults to configMap
+	Mode string `json:"mode,omitempty"`
+	// Name is the name of the ConfigMap holding the helmfile.yaml, defaults to helmfile
+	Name string `json:"name,omitempty"`
+	// Path is the file the helmfile.yaml is written to in the file mode, defaults to helmfile.yaml
+	Path string `json:"path,omitempty"`
+}
+
+// Helmfile output modes
+const (
+	// HelmfileModeConfigMap wraps the helmfile.yaml in a ConfigMap
+	HelmfileModeConfigMap = "configMap"
+	// HelmfileModeFile writes the helmfile.yaml as a file of the package
+	HelmfileModeFile = "file"
+)
+
+// KustomizeSpec defines settings only understood by the kustomize provider
+type KustomizeSpec struct {
+	// Mode is generator or helmCharts, defaults to generator
+	Mode string `json:"mode,omitempty"`
+	// ValuesFile is a values file relative to the kustomization, the inline values are applied over it
+	ValuesFile string `json:"valuesFile,omitempty"`
+	// IncludeCRDs renders the CustomResourceDefinitions of the chart
+	IncludeCRDs bool `json:"includeCRDs,omitempty"`
+	// APIVersions are the Kubernetes api versions the chart sees in .Capabilities.APIVersions
+	APIVersions []string `json:"apiVersions,omitempty"`
+}
+
+// Kustomize output modes
+const (
+	// KustomizeModeGenerator generates a HelmChartInflationGenerator listed in the generators of a kustomization
+	KustomizeModeGenerator = "generator"
+	// KustomizeModeHelmCharts generates a kustomization fragment with the chart in its helmCharts
+	KustomizeModeHelmCharts = "helmCharts"
+)
+
+// maxNameLength keeps generated names valid DNS labels
+const maxNameLength = 63
+
+// ObjectReference references a resource by name
+type ObjectReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the namespace of the HelmRelease
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// QualifiedName names a resource generated for the release with the given namespace and name outside of
+// its namespace, e.g. cluster scoped or in a shared namespace. The hash of namespace/name keeps releases
+// apart whose namespace and name join to the same string, e.g. a-b/c and a/b-c.
+func QualifiedName(namespace, name string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	return fmt.Sprintf("%s-%s-%x", namespace, name, sum[:4])
+}
+
+// RepositoryName returns the name a chart repository is registered under, derived from the host and path of
+// its URL so every release using the same repository shares it, e.g.
+// https://charts.bitnami.com/bitnami becomes charts-bitnami-com-bitnami. Names longer than a DNS label
+// are shortened and suffixed with a hash of the URL to keep them unique.
+func RepositoryName(repo string) string {
+	trimmed := repo
+	if i := strings.Index(trimmed, "://"); i >= 0 {
+		trimmed = trimmed[i+3:]
+	}
+
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(trimmed) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+
+	if len(name) > maxNameLength {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(repo)))[:8]
+		name = strings.TrimSuffix(name[:maxNameLength-len(hash)-1], "-") + "-" + hash
+	}
+
+	return name
+}
+
+// LocalObjectReference references a resource in the namespace of the referring resource, the way the
+// resources generated by the providers reference Secrets and ConfigMaps
+type LocalObjectReference struct {
+	Name string `json:"name"`
+}
+
+// LocalReference references the named resource, or nothing when the name is empty
+func LocalReference(name string) *LocalObjectReference {
+	if name == "" {
+		return nil
+	}
+	return &LocalObjectReference{Name: name}
+}
+
+// PropagationSpec defines how HelmRelease metadata is propagated to generated resources
+type PropagationSpec struct {
+	Labels      *MetadataFilter `json:"labels,omitempty"`
+	Annotations *MetadataFilter `json:"annotations,omitempty"`
+}
+
+// MetadataFilter selects metadata keys using glob patterns such as "example.com/*"
+type MetadataFilter struct {
+	// Disabled turns off propagation for this kind of metadata
+	Disabled bool `json:"disabled,omitempty"`
+	// Allow lists the key patterns to propagate, every key is allowed when empty
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists the key patterns which are never propagated, takes precedence over Allow
+	Deny []string `json:"deny,omitempty"`
+}
+
+// Output layouts decide which file each generated resource is written to
+const (
+	// OutputLayoutResource writes every resource to its own file, <release>/<kind>_<name>.yaml
+	OutputLayoutResource = "resource"
+	// OutputLayoutKind writes the resources of each kind to one file, <release>/<kind>.yaml
+	OutputLayoutKind = "kind"
+	// OutputLayoutRelease writes all resources of a release to one file, <release>.yaml
+	OutputLayoutRelease = "release"
+	// OutputLayoutNone leaves the path annotations alone
+	OutputLayoutNone = "none"
+)
+
+// OutputSpec defines the file layout of generated resources
+type OutputSpec struct {
+	// Layout is one of resource, kind, release or none, defaults to resource
+	Layout string `json:"layout,omitempty"`
+	// Dir is the directory the generated files are placed in, defaults to the directory of the HelmRelease file
+	Dir string `json:"dir,omitempty"`
+}
+
+// ProviderOverride reaches provider fields which have no portable equivalent on the HelmRelease
+type ProviderOverride struct {
+	// Kind selects the generated resource to change, defaults to the main resource of the provider
+	Kind string `json:"kind,omitempty"`
+	// Merge is deep merged into the resource following JSON merge patch (RFC 7386) semantics
+	Merge map[string]interface{} `json:"merge,omitempty"`
+	// Patches are JSON patch (RFC 6902) operations applied after Merge
+	Patches []JSONPatchOperation `json:"patches,omitempty"`
+}
+
+// JSONPatchOperation is a single RFC 6902 operation, only add, remove and replace are supported
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
 }
@@ -0,0 +1,36 @@
+package types
+
+import "testing"
+
+// TestRepositoryName tests the names chart repositories are registered under
+func TestRepositoryName(t *testing.T) {
+	tests := []struct {
+		repo     string
+		expected string
+	}{
+		{"https://charts.bitnami.com/bitnami", "charts-bitnami-com-bitnami"},
+		{"https://charts.bitnami.com/bitnami/", "charts-bitnami-com-bitnami"},
+		{"oci://ghcr.io/Stefanprodan/Charts", "ghcr-io-stefanprodan-charts"},
+		{"https://helm.github.io/examples", "helm-github-io-examples"},
+		{"https://example.com/a/very/long/path/to/a/chart/repository/which/goes/on/and/on", "example-com-a-very-long-path-to-a-chart-repository-whi-fb7916c5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			name := RepositoryName(tt.repo)
+			if name != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, name)
+			}
+			if len(name) > 63 {
+				t.Errorf("Expected a name of at most 63 characters, got %d", len(name))
+			}
+		})
+	}
+}
+
+// TestQualifiedName tests that releases whose namespace and name join to the same string get different names
+func TestQualifiedName(t *testing.T) {
+	if QualifiedName("a-b", "c") == QualifiedName("a", "b-c") {
+		t.Errorf("Expected a-b/c and a/b-c to get different names, both got %s", QualifiedName("a", "b-c"))
+	}
+}
@@ -0,0 +1,123 @@
+package values
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+)
+
+// ValidateSet checks that every set value has a name and exactly one of a value or a source
+func ValidateSet(sets []types.SetValue) error {
+	for _, set := range sets {
+		if _, err := SplitPath(set.Name); err != nil {
+			return err
+		}
+
+		if set.ValueFrom == nil {
+			continue
+		}
+		if set.Value != "" {
+			return fmt.Errorf("set value %s has both a value and valueFrom", set.Name)
+		}
+
+		if (set.ValueFrom.SecretKeyRef != nil) == (set.ValueFrom.ConfigMapKeyRef != nil) {
+			return fmt.Errorf("set value %s needs exactly one of secretKeyRef or configMapKeyRef", set.Name)
+		}
+		if ref := KeyRef(set.ValueFrom); ref.Name == "" || ref.Key == "" {
+			return fmt.Errorf("set value %s needs a name and key to read from", set.Name)
+		}
+	}
+	return nil
+}
+
+// KeyRef returns the Secret or ConfigMap key selected by the source
+func KeyRef(source *types.ValueSource) *types.KeySelector {
+	if source.SecretKeyRef != nil {
+		return source.SecretKeyRef
+	}
+	return source.ConfigMapKeyRef
+}
+
+// ApplySet returns base with the literal set values applied the way helm --set does. Values read from a
+// Secret or ConfigMap are skipped, they are only known in the cluster. base is not modified.
+func ApplySet(base map[string]interface{}, sets []types.SetValue) (map[string]interface{}, error) {
+	if err := ValidateSet(sets); err != nil {
+		return nil, err
+	}
+
+	result := deepCopy(base)
+	for _, set := range sets {
+		if set.ValueFrom != nil {
+			continue
+		}
+		if result == nil {
+			result = map[string]interface{}{}
+		}
+
+		path, _ := SplitPath(set.Name)
+		current := result
+		for _, key := range path[:len(path)-1] {
+			next, ok := current[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				current[key] = next
+			}
+			current = next
+		}
+		current[path[len(path)-1]] = ParseValue(set.Value)
+	}
+	return result, nil
+}
+
+// SplitPath splits a helm --set name into its keys. Dots escaped with a backslash are part of the key,
+// list indexes are not supported.
+func SplitPath(name string) ([]string, error) {
+	if name == "" {
+		return nil, fmt.Errorf("set value without a name")
+	}
+	if strings.ContainsAny(name, "[]") {
+		return nil, fmt.Errorf("set value %s: list indexes are not supported", name)
+	}
+
+	var path []string
+	var key strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '\\' && i+1 < len(name) && name[i+1] == '.':
+			key.WriteByte('.')
+			i++
+		case name[i] == '.':
+			path = append(path, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(name[i])
+		}
+	}
+	path = append(path, key.String())
+
+	for _, k := range path {
+		if k == "" {
+			return nil, fmt.Errorf("set value %s has an empty key", name)
+		}
+	}
+	return path, nil
+}
+
+// ParseValue converts a helm --set value to the type Helm gives it: booleans, null and integers are
+// typed, everything else is a string
+func ParseValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil && (value == "0" || !strings.HasPrefix(value, "0")) {
+		return i
+	}
+	return value
+}
@@ -0,0 +1,72 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+)
+
+func TestApplySet(t *testing.T) {
+	base := map[string]interface{}{
+		"image": map[string]interface{}{"repository": "nginx", "tag": "1.0"},
+		"debug": "yes",
+	}
+	sets := []types.SetValue{
+		{Name: "image.tag", Value: "1.1"},
+		{Name: "replicaCount", Value: "3"},
+		{Name: "debug", Value: "false"},
+		{Name: "podAnnotations.prometheus\\.io/scrape", Value: "true"},
+		{Name: "zone", Value: "007"},
+		{Name: "password", ValueFrom: &types.ValueSource{SecretKeyRef: &types.KeySelector{Name: "db", Key: "password"}}},
+	}
+
+	expected := map[string]interface{}{
+		"image":          map[string]interface{}{"repository": "nginx", "tag": "1.1"},
+		"replicaCount":   int64(3),
+		"debug":          false,
+		"podAnnotations": map[string]interface{}{"prometheus.io/scrape": true},
+		"zone":           "007",
+	}
+
+	result, err := ApplySet(base, sets)
+	if err != nil {
+		t.Fatalf("ApplySet failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// base is left untouched
+	if base["image"].(map[string]interface{})["tag"] != "1.0" {
+		t.Error("Expected base not to be modified")
+	}
+}
+
+func TestValidateSet(t *testing.T) {
+	secret := &types.KeySelector{Name: "db", Key: "password"}
+	tests := []struct {
+		name  string
+		set   types.SetValue
+		valid bool
+	}{
+		{"literal", types.SetValue{Name: "a.b", Value: "1"}, true},
+		{"secret", types.SetValue{Name: "a", ValueFrom: &types.ValueSource{SecretKeyRef: secret}}, true},
+		{"no name", types.SetValue{Value: "1"}, false},
+		{"empty key", types.SetValue{Name: "a..b", Value: "1"}, false},
+		{"list index", types.SetValue{Name: "a[0]", Value: "1"}, false},
+		{"value and source", types.SetValue{Name: "a", Value: "1", ValueFrom: &types.ValueSource{SecretKeyRef: secret}}, false},
+		{"two sources", types.SetValue{Name: "a", ValueFrom: &types.ValueSource{SecretKeyRef: secret, ConfigMapKeyRef: secret}}, false},
+		{"no source", types.SetValue{Name: "a", ValueFrom: &types.ValueSource{}}, false},
+		{"no key", types.SetValue{Name: "a", ValueFrom: &types.ValueSource{SecretKeyRef: &types.KeySelector{Name: "db"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSet([]types.SetValue{tt.set})
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package values implements the Helm values handling shared by the providers.
+package values
+
+import "strings"
+
+// templateEscaper renders Go template delimiters as literal text
+var templateEscaper = strings.NewReplacer("{{", `{{ "{{" }}`, "}}", `{{ "}}" }}`)
+
+// EscapeTemplate escapes the Go template delimiters in values rendered as a template by a provider, so
+// values which are templates for the chart itself reach it unchanged
+func EscapeTemplate(s string) string {
+	return templateEscaper.Replace(s)
+}
+
+// EscapeTemplateValues returns a copy of the values with the Go template delimiters of every key and
+// string escaped, for values objects which are part of a template
+func EscapeTemplateValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+
+	escaped := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		escaped[EscapeTemplate(k)] = escapeTemplateValue(v)
+	}
+	return escaped
+}
+
+// escapeTemplateValue escapes the strings of a single value, recursing into maps and lists
+func escapeTemplateValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return EscapeTemplate(v)
+	case map[string]interface{}:
+		return EscapeTemplateValues(v)
+	case []interface{}:
+		escaped := make([]interface{}, len(v))
+		for i, item := range v {
+			escaped[i] = escapeTemplateValue(item)
+		}
+		return escaped
+	default:
+		return v
+	}
+}
+
+// Merge deep merges the overrides over base the way Helm coalesces values files. Maps are merged
+// recursively, a nil value removes the key and any other value replaces the previous one. Neither
+// base nor the overrides are modified.
+func Merge(base map[string]interface{}, overrides ...map[string]interface{}) map[string]interface{} {
+	result := deepCopy(base)
+	for _, override := range overrides {
+		result = mergeMaps(result, override)
+	}
+	return result
+}
+
+// mergeMaps merges src into dst and returns dst
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+
+	for k, v := range src {
+		if v == nil {
+			delete(dst, k)
+			continue
+		}
+
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[k] = mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[k] = deepCopyValue(v)
+	}
+
+	return dst
+}
+
+// deepCopy returns a copy of m sharing no maps or slices with it
+func deepCopy(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[k] = deepCopyValue(v)
+	}
+	return result
+}
+
+// deepCopyValue returns a copy of v sharing no maps or slices with it
+func deepCopyValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return deepCopy(value)
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = deepCopyValue(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
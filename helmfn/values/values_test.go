@@ -0,0 +1,90 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := map[string]interface{}{
+		"replicaCount": 2,
+		"service": map[string]interface{}{
+			"type": "ClusterIP",
+			"port": 80,
+		},
+		"tolerations": []interface{}{"a"},
+	}
+	override := map[string]interface{}{
+		"replicaCount": 3,
+		"service": map[string]interface{}{
+			"port": 443,
+		},
+		"tolerations": nil,
+	}
+
+	expected := map[string]interface{}{
+		"replicaCount": 3,
+		"service": map[string]interface{}{
+			"type": "ClusterIP",
+			"port": 443,
+		},
+	}
+
+	result := Merge(base, override)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// The inputs are left untouched
+	if base["replicaCount"] != 2 || base["service"].(map[string]interface{})["port"] != 80 {
+		t.Errorf("Expected base values to be unchanged, got %v", base)
+	}
+}
+
+func TestMergeWithoutBase(t *testing.T) {
+	result := Merge(nil, map[string]interface{}{"replicaCount": 3})
+	if result["replicaCount"] != 3 {
+		t.Errorf("Expected replicaCount 3, got %v", result)
+	}
+
+	if result := Merge(nil); result != nil {
+		t.Errorf("Expected nil when there is nothing to merge, got %v", result)
+	}
+}
+
+func TestEscapeTemplate(t *testing.T) {
+	escaped := EscapeTemplate("greeting: Hello {{ .Values.name }}\nport: 80\n")
+	expected := "greeting: Hello {{ \"{{\" }} .Values.name {{ \"}}\" }}\nport: 80\n"
+	if escaped != expected {
+		t.Errorf("Expected %q, got %q", expected, escaped)
+	}
+
+	if escaped := EscapeTemplate("replicaCount: 2\n"); escaped != "replicaCount: 2\n" {
+		t.Errorf("Expected values without delimiters to be unchanged, got %q", escaped)
+	}
+}
+
+func TestEscapeTemplateValues(t *testing.T) {
+	chartValues := map[string]interface{}{
+		"name":     "{{ .Release.Name }}",
+		"replicas": 2,
+		"labels":   map[string]interface{}{"{{ .key }}": "app"},
+		"args":     []interface{}{"--release={{ .Release.Name }}", true},
+	}
+
+	escaped := EscapeTemplateValues(chartValues)
+	expected := map[string]interface{}{
+		"name":     `{{ "{{" }} .Release.Name {{ "}}" }}`,
+		"replicas": 2,
+		"labels":   map[string]interface{}{`{{ "{{" }} .key {{ "}}" }}`: "app"},
+		"args":     []interface{}{`--release={{ "{{" }} .Release.Name {{ "}}" }}`, true},
+	}
+	if !reflect.DeepEqual(escaped, expected) {
+		t.Errorf("Expected %v, got %v", expected, escaped)
+	}
+
+	// The values are copied, not modified
+	if chartValues["name"] != "{{ .Release.Name }}" {
+		t.Error("Expected EscapeTemplateValues not to modify its argument")
+	}
+}
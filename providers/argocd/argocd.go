@@ -1,8 +1,27 @@
 package argocd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultServer is the in-cluster API server ArgoCD deploys to by default
+	DefaultServer = "https://kubernetes.default.svc"
+	// DefaultProject is the project every ArgoCD installation ships with
+	DefaultProject = "default"
+	// SecretTypeLabel tells ArgoCD what a Secret in its namespace configures
+	SecretTypeLabel = "argocd.argoproj.io/secret-type"
+	// valuesRef is the ref name of the first git source holding values files
+	valuesRef = "values"
 )
 
 // ArgoCDApplication represents an ArgoCD Application resource
@@ -14,8 +33,157 @@ type ArgoCDApplication struct {
 
 // ArgoCDApplicationSpec defines the desired state of ArgoCD Application
 type ArgoCDApplicationSpec struct {
-	// For phase 1, we'll keep this minimal
-	// Full spec will be added in future phases
+	Project     string                    `json:"project,omitempty"`
+	Destination ArgoCDDestination         `json:"destination"`
+	Source      *ArgoCDApplicationSource  `json:"source,omitempty"`
+	Sources     []ArgoCDApplicationSource `json:"sources,omitempty"`
+	SyncPolicy  ArgoCDSyncPolicy          `json:"syncPolicy"`
+	// IgnoreDifferences lists resource fields excluded from the live state comparison
+	IgnoreDifferences []ArgoCDIgnoreDifferences `json:"ignoreDifferences,omitempty"`
+	// RevisionHistoryLimit is the number of synced revisions kept in the Application history
+	RevisionHistoryLimit *int64 `json:"revisionHistoryLimit,omitempty"`
+}
+
+// ArgoCDSyncPolicy defines when and how an Application is synced
+type ArgoCDSyncPolicy struct {
+	Automated   *ArgoCDSyncPolicyAutomated `json:"automated,omitempty"`
+	SyncOptions []string                   `json:"syncOptions,omitempty"`
+	Retry       *ArgoCDRetryStrategy       `json:"retry,omitempty"`
+}
+
+// ArgoCDSyncPolicyAutomated enables automated syncs
+type ArgoCDSyncPolicyAutomated struct {
+	Prune    bool `json:"prune,omitempty"`
+	SelfHeal bool `json:"selfHeal,omitempty"`
+}
+
+// ArgoCDRetryStrategy defines how failed syncs are retried
+type ArgoCDRetryStrategy struct {
+	Limit   int64          `json:"limit,omitempty"`
+	Backoff *ArgoCDBackoff `json:"backoff,omitempty"`
+}
+
+// ArgoCDBackoff defines the delay between sync retries
+type ArgoCDBackoff struct {
+	Duration    string `json:"duration,omitempty"`
+	Factor      *int64 `json:"factor,omitempty"`
+	MaxDuration string `json:"maxDuration,omitempty"`
+}
+
+// ArgoCDIgnoreDifferences excludes fields of matching resources from the diff
+type ArgoCDIgnoreDifferences struct {
+	Group             string   `json:"group,omitempty"`
+	Kind              string   `json:"kind"`
+	JSONPointers      []string `json:"jsonPointers,omitempty"`
+	JQPathExpressions []string `json:"jqPathExpressions,omitempty"`
+}
+
+// ArgoCDDestination defines the cluster and namespace an Application deploys to
+type ArgoCDDestination struct {
+	Name      string `json:"name,omitempty"`
+	Server    string `json:"server,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ArgoCDApplicationSource defines a single source of an Application, either a Helm chart or a git
+// repository only referenced by the other sources through Ref
+type ArgoCDApplicationSource struct {
+	RepoURL        string      `json:"repoURL"`
+	Chart          string      `json:"chart,omitempty"`
+	TargetRevision string      `json:"targetRevision,omitempty"`
+	Ref            string      `json:"ref,omitempty"`
+	Helm           *ArgoCDHelm `json:"helm,omitempty"`
+}
+
+// ArgoCDHelm defines the Helm specific options of a chart source
+type ArgoCDHelm struct {
+	ValueFiles      []string               `json:"valueFiles,omitempty"`
+	Parameters      []ArgoCDHelmParameter  `json:"parameters,omitempty"`
+	PassCredentials bool                   `json:"passCredentials,omitempty"`
+	Values          string                 `json:"values,omitempty"`
+	ValuesObject    map[string]interface{} `json:"valuesObject,omitempty"`
+}
+
+// ArgoCDHelmParameter overrides a single value like helm --set
+type ArgoCDHelmParameter struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ArgoCDApplicationSet represents an ArgoCD ApplicationSet resource
+type ArgoCDApplicationSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ArgoCDApplicationSetSpec `json:"spec,omitempty"`
+}
+
+// ArgoCDApplicationSetSpec defines the desired state of ArgoCD ApplicationSet
+type ArgoCDApplicationSetSpec struct {
+	GoTemplate        bool                         `json:"goTemplate,omitempty"`
+	GoTemplateOptions []string                     `json:"goTemplateOptions,omitempty"`
+	Generators        []ArgoCDGenerator            `json:"generators"`
+	Template          ArgoCDApplicationSetTemplate `json:"template"`
+}
+
+// ArgoCDGenerator defines a single ApplicationSet generator, only one field is set
+type ArgoCDGenerator struct {
+	List     *ArgoCDListGenerator    `json:"list,omitempty"`
+	Clusters *ArgoCDClusterGenerator `json:"clusters,omitempty"`
+	Matrix   *ArgoCDMatrixGenerator  `json:"matrix,omitempty"`
+}
+
+// ArgoCDListGenerator generates parameters from a fixed list of elements
+type ArgoCDListGenerator struct {
+	Elements []map[string]interface{} `json:"elements"`
+}
+
+// ArgoCDClusterGenerator generates parameters from the clusters registered in ArgoCD
+type ArgoCDClusterGenerator struct {
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ArgoCDMatrixGenerator combines the parameters of two generators
+type ArgoCDMatrixGenerator struct {
+	Generators []ArgoCDGenerator `json:"generators"`
+}
+
+// ArgoCDApplicationSetTemplate is the Application template rendered for each generated parameter set
+type ArgoCDApplicationSetTemplate struct {
+	Metadata ArgoCDTemplateMeta    `json:"metadata"`
+	Spec     ArgoCDApplicationSpec `json:"spec"`
+}
+
+// ArgoCDTemplateMeta is the metadata of the templated Application
+type ArgoCDTemplateMeta struct {
+	Name string `json:"name"`
+}
+
+// ArgoCDAppProject represents an ArgoCD AppProject resource
+type ArgoCDAppProject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ArgoCDAppProjectSpec `json:"spec,omitempty"`
+}
+
+// ArgoCDAppProjectSpec defines what the Applications of a project may deploy and where
+type ArgoCDAppProjectSpec struct {
+	Description              string              `json:"description,omitempty"`
+	SourceRepos              []string            `json:"sourceRepos"`
+	Destinations             []ArgoCDDestination `json:"destinations"`
+	ClusterResourceWhitelist []ArgoCDGroupKind   `json:"clusterResourceWhitelist,omitempty"`
+}
+
+// ArgoCDGroupKind identifies a kind of resource allowed in a project
+type ArgoCDGroupKind struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+}
+
+// ArgoCDRepositorySecret represents a Secret declaring a repository and its credentials to ArgoCD
+type ArgoCDRepositorySecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	StringData        map[string]string `json:"stringData"`
 }
 
 // ArgoCDProvider handles the transformation of HelmRelease to ArgoCD Application
@@ -28,6 +196,11 @@ func NewArgoCDProvider() *ArgoCDProvider {
 
 // GenerateApplication creates an ArgoCD Application resource from a HelmRelease
 func (p *ArgoCDProvider) GenerateApplication(helmRelease *types.HelmRelease) (*ArgoCDApplication, error) {
+	spec, err := buildApplicationSpec(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the ArgoCD Application with basic structure
 	app := &ArgoCDApplication{
 		TypeMeta: metav1.TypeMeta{
@@ -38,10 +211,493 @@ func (p *ArgoCDProvider) GenerateApplication(helmRelease *types.HelmRelease) (*A
 			Name:      helmRelease.ObjectMeta.Name,
 			Namespace: "argocd", // Default ArgoCD namespace
 		},
-		Spec: ArgoCDApplicationSpec{
-			// Minimal spec for phase 1
-		},
+		Spec: *spec,
 	}
 
 	return app, nil
 }
+
+// UsesAppProject reports whether the HelmRelease asks for its own AppProject
+func (p *ArgoCDProvider) UsesAppProject(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.ArgoCD != nil && helmRelease.Spec.ArgoCD.AppProject != nil
+}
+
+// GenerateAppProject creates a least privilege ArgoCD AppProject for a HelmRelease. Sources are limited to
+// the chart repository and the git repositories of values files, destinations to the release namespace on
+// the clusters the release is deployed to, and cluster scoped resources to the ones explicitly allowed.
+func (p *ArgoCDProvider) GenerateAppProject(helmRelease *types.HelmRelease) (*ArgoCDAppProject, error) {
+	if !p.UsesAppProject(helmRelease) {
+		return nil, fmt.Errorf("spec.argocd.appProject is not set")
+	}
+	project := helmRelease.Spec.ArgoCD.AppProject
+
+	sourceRepos := []string{repoURL(helmRelease.Spec.Chart.Repo)}
+	for _, valuesFile := range helmRelease.Spec.ValuesFiles {
+		if valuesFile.Repo != "" && !slices.Contains(sourceRepos, valuesFile.Repo) {
+			sourceRepos = append(sourceRepos, valuesFile.Repo)
+		}
+	}
+
+	var whitelist []ArgoCDGroupKind
+	if project.InstallsCRDs {
+		whitelist = append(whitelist, ArgoCDGroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"})
+	}
+	for _, gk := range project.ClusterResources {
+		if gk.Kind == "" {
+			return nil, fmt.Errorf("cluster resource without a kind")
+		}
+		whitelist = append(whitelist, ArgoCDGroupKind{Group: gk.Group, Kind: gk.Kind})
+	}
+
+	appProject := &ArgoCDAppProject{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "argoproj.io/v1alpha1",
+			Kind:       "AppProject",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      projectName(helmRelease),
+			Namespace: "argocd", // Default ArgoCD namespace
+		},
+		Spec: ArgoCDAppProjectSpec{
+			Description:              fmt.Sprintf("Project of the HelmRelease %s/%s", helmRelease.ObjectMeta.Namespace, helmRelease.ObjectMeta.Name),
+			SourceRepos:              sourceRepos,
+			Destinations:             p.projectDestinations(helmRelease),
+			ClusterResourceWhitelist: whitelist,
+		},
+	}
+
+	return appProject, nil
+}
+
+// UsesRepositorySecret reports whether the chart repository needs credentials
+func (p *ArgoCDProvider) UsesRepositorySecret(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.Chart.Auth != nil
+}
+
+// GenerateRepositorySecret creates the Secret declaring the chart repository to ArgoCD. ArgoCD only reads
+// credentials from Secrets in its own namespace, so the Secret holds the connection settings and the
+// credentials are filled in from the Secrets referenced by spec.chart.auth. It is named after the
+// repository so releases pulling from the same repository share it.
+func (p *ArgoCDProvider) GenerateRepositorySecret(helmRelease *types.HelmRelease) (*ArgoCDRepositorySecret, error) {
+	if !p.UsesRepositorySecret(helmRelease) {
+		return nil, fmt.Errorf("spec.chart.auth is not set")
+	}
+	repo := helmRelease.Spec.Chart.Repo
+	if repo == "" {
+		return nil, fmt.Errorf("spec.chart.repo is required to authenticate to the chart repository")
+	}
+
+	stringData := map[string]string{
+		"type": "helm",
+		"url":  repoURL(repo),
+	}
+	if strings.HasPrefix(repo, "oci://") {
+		stringData["enableOCI"] = "true"
+	}
+	if helmRelease.Spec.Chart.Auth.InsecureSkipTLSVerify {
+		stringData["insecure"] = "true"
+	}
+
+	sum := sha256.Sum256([]byte(repo))
+	secret := &ArgoCDRepositorySecret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "repo-" + hex.EncodeToString(sum[:])[:8],
+			Namespace: "argocd", // Default ArgoCD namespace
+			Labels:    map[string]string{SecretTypeLabel: "repository"},
+		},
+		StringData: stringData,
+	}
+
+	return secret, nil
+}
+
+// repoURL returns the chart repository as ArgoCD expects it, OCI registries without their oci:// scheme.
+func repoURL(repo string) string {
+	return strings.TrimPrefix(repo, "oci://")
+}
+
+// projectName returns the project the Applications of a HelmRelease belong to
+func projectName(helmRelease *types.HelmRelease) string {
+	if helmRelease.Spec.ArgoCD == nil || helmRelease.Spec.ArgoCD.AppProject == nil {
+		return DefaultProject
+	}
+	if name := helmRelease.Spec.ArgoCD.AppProject.Name; name != "" {
+		return name
+	}
+	return helmRelease.ObjectMeta.Name
+}
+
+// projectDestinations returns the destinations allowed in the project of a HelmRelease. Clusters found by
+// the clusters and matrix generators are only known to ArgoCD, so any server is allowed for them but still
+// only the namespaces of the release.
+func (p *ArgoCDProvider) projectDestinations(helmRelease *types.HelmRelease) []ArgoCDDestination {
+	anyServer := false
+	if p.UsesApplicationSet(helmRelease) {
+		generator := applicationSetGenerator(helmRelease)
+		anyServer = generator == types.GeneratorClusters || generator == types.GeneratorMatrix
+	}
+
+	if len(helmRelease.Spec.Destinations) == 0 {
+		server := DefaultServer
+		if anyServer {
+			server = "*"
+		}
+		return []ArgoCDDestination{{Server: server, Namespace: helmRelease.ObjectMeta.Namespace}}
+	}
+
+	var destinations []ArgoCDDestination
+	for _, destination := range helmRelease.Spec.Destinations {
+		namespace := destination.Namespace
+		if namespace == "" {
+			namespace = helmRelease.ObjectMeta.Namespace
+		}
+
+		allowed := ArgoCDDestination{Name: destination.Name, Namespace: namespace}
+		switch {
+		case anyServer:
+			allowed = ArgoCDDestination{Server: "*", Namespace: namespace}
+		case destination.Server != "":
+			allowed = ArgoCDDestination{Server: destination.Server, Namespace: namespace}
+		}
+
+		if !slices.Contains(destinations, allowed) {
+			destinations = append(destinations, allowed)
+		}
+	}
+	return destinations
+}
+
+// UsesApplicationSet reports whether the HelmRelease asks for an ApplicationSet instead of an Application
+func (p *ArgoCDProvider) UsesApplicationSet(helmRelease *types.HelmRelease) bool {
+	if len(helmRelease.Spec.Destinations) > 0 {
+		return true
+	}
+	return helmRelease.Spec.ArgoCD != nil && helmRelease.Spec.ArgoCD.ApplicationSet != nil
+}
+
+// GenerateApplicationSet creates an ArgoCD ApplicationSet resource from a HelmRelease deployed to several
+// clusters. The list generator creates one Application per destination with the destination values merged
+// into the release values. The clusters generator creates one Application per cluster matching the cluster
+// selector. The matrix generator creates one Application per destination on every matching cluster.
+func (p *ArgoCDProvider) GenerateApplicationSet(helmRelease *types.HelmRelease) (*ArgoCDApplicationSet, error) {
+	generator := applicationSetGenerator(helmRelease)
+
+	spec, err := buildApplicationSpec(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	// Per destination values are rendered into the string values field since objects cannot be templated
+	chartSource := spec.Source
+	if chartSource == nil {
+		chartSource = &spec.Sources[0]
+	}
+	// Values left in the template are rendered by the ApplicationSet controller, delimiters meant for the
+	// chart are escaped so they reach it unchanged
+	if chartSource.Helm != nil {
+		chartSource.Helm.ValuesObject = values.EscapeTemplateValues(chartSource.Helm.ValuesObject)
+		for i := range chartSource.Helm.Parameters {
+			chartSource.Helm.Parameters[i].Value = values.EscapeTemplate(chartSource.Helm.Parameters[i].Value)
+		}
+	}
+	templateValues := func() {
+		if chartSource.Helm == nil {
+			chartSource.Helm = &ArgoCDHelm{}
+		}
+		chartSource.Helm.ValuesObject = nil
+		chartSource.Helm.Values = "{{ .values }}"
+	}
+
+	release := helmRelease.ObjectMeta.Name
+	clusters := &ArgoCDClusterGenerator{Selector: helmRelease.Spec.ClusterSelector}
+
+	var generators []ArgoCDGenerator
+	var name string
+	switch generator {
+	case types.GeneratorList:
+		if len(helmRelease.Spec.Destinations) == 0 {
+			return nil, fmt.Errorf("the list generator needs spec.destinations")
+		}
+
+		elements, byServer, err := destinationElements(helmRelease)
+		if err != nil {
+			return nil, err
+		}
+
+		generators = []ArgoCDGenerator{{List: &ArgoCDListGenerator{Elements: elements}}}
+		name = release + "-{{ .name }}"
+		spec.Destination = ArgoCDDestination{Name: "{{ .name }}", Namespace: "{{ .namespace }}"}
+		if byServer {
+			spec.Destination = ArgoCDDestination{Server: "{{ .server }}", Namespace: "{{ .namespace }}"}
+		}
+		templateValues()
+
+	case types.GeneratorClusters:
+		if len(helmRelease.Spec.Destinations) > 0 {
+			return nil, fmt.Errorf("the clusters generator does not support spec.destinations, use the list or matrix generator")
+		}
+
+		generators = []ArgoCDGenerator{{Clusters: clusters}}
+		name = release + "-{{ .nameNormalized }}"
+		spec.Destination.Server = "{{ .server }}"
+
+	case types.GeneratorMatrix:
+		if len(helmRelease.Spec.Destinations) == 0 {
+			return nil, fmt.Errorf("the matrix generator needs spec.destinations")
+		}
+
+		elements, _, err := destinationElements(helmRelease)
+		if err != nil {
+			return nil, err
+		}
+
+		// The cluster generator owns the name and server parameters, the destinations become variants
+		for _, element := range elements {
+			element["destination"] = element["name"]
+			delete(element, "name")
+			delete(element, "server")
+		}
+
+		generators = []ArgoCDGenerator{{Matrix: &ArgoCDMatrixGenerator{
+			Generators: []ArgoCDGenerator{
+				{Clusters: clusters},
+				{List: &ArgoCDListGenerator{Elements: elements}},
+			},
+		}}}
+		name = release + "-{{ .nameNormalized }}-{{ .destination }}"
+		spec.Destination = ArgoCDDestination{Server: "{{ .server }}", Namespace: "{{ .namespace }}"}
+		templateValues()
+
+	default:
+		return nil, fmt.Errorf("unsupported ApplicationSet generator: %s", generator)
+	}
+
+	appSet := &ArgoCDApplicationSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "argoproj.io/v1alpha1",
+			Kind:       "ApplicationSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      release,
+			Namespace: "argocd", // Default ArgoCD namespace
+		},
+		Spec: ArgoCDApplicationSetSpec{
+			GoTemplate:        true,
+			GoTemplateOptions: []string{"missingkey=error"},
+			Generators:        generators,
+			Template: ArgoCDApplicationSetTemplate{
+				Metadata: ArgoCDTemplateMeta{Name: name},
+				Spec:     *spec,
+			},
+		},
+	}
+
+	return appSet, nil
+}
+
+// applicationSetGenerator returns the generator requested by the HelmRelease, defaulting to list when
+// destinations are set and clusters otherwise
+func applicationSetGenerator(helmRelease *types.HelmRelease) string {
+	if helmRelease.Spec.ArgoCD != nil && helmRelease.Spec.ArgoCD.ApplicationSet != nil && helmRelease.Spec.ArgoCD.ApplicationSet.Generator != "" {
+		return helmRelease.Spec.ArgoCD.ApplicationSet.Generator
+	}
+	if len(helmRelease.Spec.Destinations) == 0 {
+		return types.GeneratorClusters
+	}
+	return types.GeneratorList
+}
+
+// destinationElements returns the list generator elements of the destinations, each carrying the release
+// values merged with the destination values as a YAML string. It also reports whether the destinations are
+// addressed by server URL rather than by cluster name, which must be the same for all of them.
+func destinationElements(helmRelease *types.HelmRelease) ([]map[string]interface{}, bool, error) {
+	var elements []map[string]interface{}
+	withServer := 0
+	for _, destination := range helmRelease.Spec.Destinations {
+		if destination.Name == "" {
+			return nil, false, fmt.Errorf("destination without a name")
+		}
+		if destination.Server != "" {
+			withServer++
+		}
+
+		namespace := destination.Namespace
+		if namespace == "" {
+			namespace = helmRelease.ObjectMeta.Namespace
+		}
+
+		valuesYAML := ""
+		if merged := values.Merge(helmRelease.Spec.Values, destination.Values); len(merged) > 0 {
+			valuesBytes, err := yaml.Marshal(merged)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to marshal values of destination %s: %w", destination.Name, err)
+			}
+			valuesYAML = string(valuesBytes)
+		}
+
+		elements = append(elements, map[string]interface{}{
+			"name":      destination.Name,
+			"server":    destination.Server,
+			"namespace": namespace,
+			"values":    valuesYAML,
+		})
+	}
+
+	if withServer != 0 && withServer != len(elements) {
+		return nil, false, fmt.Errorf("either all destinations or none must set a server")
+	}
+
+	return elements, withServer != 0, nil
+}
+
+// buildApplicationSpec creates the Application spec shared by Applications and ApplicationSet templates.
+// Values files kept in git repositories turn it into a multi-source spec (ArgoCD 2.6+) where the chart
+// source references each git source through its ref, e.g. $values/envs/prod.yaml.
+func buildApplicationSpec(helmRelease *types.HelmRelease) (*ArgoCDApplicationSpec, error) {
+	chartSource := ArgoCDApplicationSource{
+		RepoURL:        repoURL(helmRelease.Spec.Chart.Repo),
+		Chart:          helmRelease.Spec.Chart.Name,
+		TargetRevision: helmRelease.Spec.Chart.Version,
+	}
+
+	// Values files packaged in the chart are resolved relative to it, before any other values file
+	helm := &ArgoCDHelm{
+		ValueFiles:   slices.Clone(helmRelease.Spec.Chart.ValuesFiles),
+		ValuesObject: helmRelease.Spec.Values,
+	}
+
+	// Git sources are keyed by repository and revision so files from the same checkout share a ref
+	var gitSources []ArgoCDApplicationSource
+	refs := map[string]string{}
+	for _, valuesFile := range helmRelease.Spec.ValuesFiles {
+		if valuesFile.Path == "" {
+			return nil, fmt.Errorf("values file without a path")
+		}
+
+		if valuesFile.Repo == "" {
+			helm.ValueFiles = append(helm.ValueFiles, valuesFile.Path)
+			continue
+		}
+
+		key := valuesFile.Repo + "@" + valuesFile.Revision
+		ref, ok := refs[key]
+		if !ok {
+			ref = valuesRef
+			if len(gitSources) > 0 {
+				ref = fmt.Sprintf("%s%d", valuesRef, len(gitSources)+1)
+			}
+			refs[key] = ref
+			gitSources = append(gitSources, ArgoCDApplicationSource{
+				RepoURL:        valuesFile.Repo,
+				TargetRevision: valuesFile.Revision,
+				Ref:            ref,
+			})
+		}
+		helm.ValueFiles = append(helm.ValueFiles, "$"+ref+"/"+valuesFile.Path)
+	}
+
+	// Values read from a Secret or ConfigMap have no ArgoCD equivalent and are skipped
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return nil, err
+	}
+	for _, set := range helmRelease.Spec.Set {
+		if set.ValueFrom == nil {
+			helm.Parameters = append(helm.Parameters, ArgoCDHelmParameter{Name: set.Name, Value: set.Value})
+		}
+	}
+
+	// Credentials are only sent to the repository host unless explicitly passed on
+	if auth := helmRelease.Spec.Chart.Auth; auth != nil {
+		helm.PassCredentials = auth.PassCredentials
+	}
+
+	if len(helm.ValueFiles) > 0 || len(helm.Parameters) > 0 || len(helm.ValuesObject) > 0 || helm.PassCredentials {
+		chartSource.Helm = helm
+	}
+
+	spec := &ArgoCDApplicationSpec{
+		Project: projectName(helmRelease),
+		Destination: ArgoCDDestination{
+			Server:    DefaultServer,
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+	}
+
+	if len(gitSources) == 0 {
+		spec.Source = &chartSource
+	} else {
+		spec.Sources = append([]ArgoCDApplicationSource{chartSource}, gitSources...)
+	}
+
+	applySyncSpec(spec, helmRelease.Spec.Sync)
+	applyLifecycleSpec(spec, helmRelease.Spec.Lifecycle)
+
+	return spec, nil
+}
+
+// applySyncSpec maps the portable sync settings onto the sync policy, sync options and ignored differences
+func applySyncSpec(spec *ArgoCDApplicationSpec, sync *types.SyncSpec) {
+	if sync == nil {
+		return
+	}
+
+	if sync.Automated {
+		spec.SyncPolicy.Automated = &ArgoCDSyncPolicyAutomated{
+			Prune:    sync.Prune,
+			SelfHeal: sync.SelfHeal,
+		}
+	}
+
+	if sync.Retry != nil {
+		spec.SyncPolicy.Retry = &ArgoCDRetryStrategy{Limit: int64(sync.Retry.Limit)}
+		if backoff := sync.Retry.Backoff; backoff != nil {
+			spec.SyncPolicy.Retry.Backoff = &ArgoCDBackoff{
+				Duration:    backoff.Duration,
+				MaxDuration: backoff.MaxDuration,
+			}
+			if backoff.Factor != 0 {
+				factor := int64(backoff.Factor)
+				spec.SyncPolicy.Retry.Backoff.Factor = &factor
+			}
+		}
+	}
+
+	if sync.CreateNamespace {
+		spec.SyncPolicy.SyncOptions = append(spec.SyncPolicy.SyncOptions, "CreateNamespace=true")
+	}
+
+	if sync.ServerSideApply {
+		spec.SyncPolicy.SyncOptions = append(spec.SyncPolicy.SyncOptions, "ServerSideApply=true")
+	}
+
+	if sync.IgnoreCRDDrift {
+		spec.IgnoreDifferences = append(spec.IgnoreDifferences, ArgoCDIgnoreDifferences{
+			Group:        "apiextensions.k8s.io",
+			Kind:         "CustomResourceDefinition",
+			JSONPointers: []string{"/spec"},
+		})
+		// Without this option the ignored fields are still overwritten whenever the Application syncs
+		spec.SyncPolicy.SyncOptions = append(spec.SyncPolicy.SyncOptions, "RespectIgnoreDifferences=true")
+	}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have an ArgoCD equivalent. A suspended
+// release is no longer synced automatically and the Helm history is the Application history.
+func applyLifecycleSpec(spec *ArgoCDApplicationSpec, lifecycle *types.LifecycleSpec) {
+	if lifecycle == nil {
+		return
+	}
+
+	if lifecycle.Suspend {
+		spec.SyncPolicy.Automated = nil
+	}
+
+	if lifecycle.MaxHistory != nil {
+		limit := int64(*lifecycle.MaxHistory)
+		spec.RevisionHistoryLimit = &limit
+	}
+}
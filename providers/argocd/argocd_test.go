@@ -2,9 +2,12 @@ package argocd
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
 	"github.com/kubed-io/krm-helm-fn/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestNewArgoCDProvider(t *testing.T) {
@@ -57,3 +60,450 @@ func TestArgoCDProvider_GenerateApplicationFromExample(t *testing.T) {
 	// For argocd provider in hello world state, we just check basic structure
 	// Future tests will validate the full spec content
 }
+
+// TestArgoCDProvider_GenerateApplicationSpec tests the chart source and destination of the generated application
+func TestArgoCDProvider_GenerateApplicationSpec(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewArgoCDProvider()
+	app, err := provider.GenerateApplication(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplication failed: %v", err)
+	}
+
+	if app.Spec.Project != "default" {
+		t.Errorf("Expected project 'default', got '%s'", app.Spec.Project)
+	}
+
+	if app.Spec.Destination.Server != "https://kubernetes.default.svc" || app.Spec.Destination.Namespace != "my-system" {
+		t.Errorf("Unexpected destination %+v", app.Spec.Destination)
+	}
+
+	if app.Spec.Source == nil || len(app.Spec.Sources) != 0 {
+		t.Fatal("Expected a single source application")
+	}
+
+	source := app.Spec.Source
+	if source.Chart != "hello-world" || source.RepoURL != "https://helm.github.io/examples" || source.TargetRevision != "0.1.0" {
+		t.Errorf("Unexpected chart source %+v", source)
+	}
+
+	if source.Helm == nil || source.Helm.ValuesObject["replicaCount"] != float64(2) {
+		t.Errorf("Expected inline values in valuesObject, got %+v", source.Helm)
+	}
+}
+
+// TestArgoCDProvider_GenerateMultiSourceApplication tests values files from git repositories
+func TestArgoCDProvider_GenerateMultiSourceApplication(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.ValuesFiles = []types.ValuesFile{
+		{Path: "values-production.yaml"},
+		{Path: "envs/common.yaml", Repo: "https://github.com/example/config.git", Revision: "main"},
+		{Path: "envs/prod.yaml", Repo: "https://github.com/example/config.git", Revision: "main"},
+		{Path: "secrets/prod.yaml", Repo: "https://github.com/example/secrets.git"},
+	}
+
+	provider := NewArgoCDProvider()
+	app, err := provider.GenerateApplication(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplication failed: %v", err)
+	}
+
+	if app.Spec.Source != nil {
+		t.Error("Expected spec.source to be empty for a multi-source application")
+	}
+
+	if len(app.Spec.Sources) != 3 {
+		t.Fatalf("Expected 3 sources (chart + 2 git repositories), got %d", len(app.Spec.Sources))
+	}
+
+	expectedValueFiles := []string{
+		"values-production.yaml",
+		"$values/envs/common.yaml",
+		"$values/envs/prod.yaml",
+		"$values2/secrets/prod.yaml",
+	}
+	chartSource := app.Spec.Sources[0]
+	if chartSource.Helm == nil || !reflect.DeepEqual(chartSource.Helm.ValueFiles, expectedValueFiles) {
+		t.Errorf("Expected valueFiles %v, got %+v", expectedValueFiles, chartSource.Helm)
+	}
+
+	if app.Spec.Sources[1].Ref != "values" || app.Spec.Sources[1].TargetRevision != "main" {
+		t.Errorf("Unexpected first git source %+v", app.Spec.Sources[1])
+	}
+
+	if app.Spec.Sources[2].Ref != "values2" || app.Spec.Sources[2].RepoURL != "https://github.com/example/secrets.git" {
+		t.Errorf("Unexpected second git source %+v", app.Spec.Sources[2])
+	}
+}
+
+// TestArgoCDProvider_GenerateApplicationSetFromExample tests the list generator using the applicationset example
+func TestArgoCDProvider_GenerateApplicationSetFromExample(t *testing.T) {
+	// Load the argocd-applicationset example files
+	exampleDir := filepath.Join("..", "..", "examples", "argocd-applicationset")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewArgoCDProvider()
+	if !provider.UsesApplicationSet(helmRelease) {
+		t.Fatal("Expected a release with destinations to use an ApplicationSet")
+	}
+
+	appSet, err := provider.GenerateApplicationSet(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplicationSet failed: %v", err)
+	}
+
+	if appSet.Kind != "ApplicationSet" || appSet.ObjectMeta.Name != "my-app" || appSet.ObjectMeta.Namespace != "argocd" {
+		t.Errorf("Unexpected ApplicationSet %s %s/%s", appSet.Kind, appSet.ObjectMeta.Namespace, appSet.ObjectMeta.Name)
+	}
+
+	if len(appSet.Spec.Generators) != 1 || appSet.Spec.Generators[0].List == nil {
+		t.Fatalf("Expected a single list generator, got %+v", appSet.Spec.Generators)
+	}
+
+	elements := appSet.Spec.Generators[0].List.Elements
+	if len(elements) != 2 {
+		t.Fatalf("Expected 2 elements, got %d", len(elements))
+	}
+
+	if elements[1]["values"] != "replicaCount: 5\n" {
+		t.Errorf("Expected destination values to override release values, got %q", elements[1]["values"])
+	}
+
+	if elements[0]["namespace"] != "my-system" {
+		t.Errorf("Expected destination namespace to default to the release namespace, got %v", elements[0]["namespace"])
+	}
+
+	template := appSet.Spec.Template
+	if template.Metadata.Name != "my-app-{{ .name }}" {
+		t.Errorf("Unexpected template name '%s'", template.Metadata.Name)
+	}
+
+	if template.Spec.Destination.Server != "{{ .server }}" || template.Spec.Destination.Name != "" {
+		t.Errorf("Expected destinations addressed by server, got %+v", template.Spec.Destination)
+	}
+
+	if template.Spec.Source.Helm.Values != "{{ .values }}" || template.Spec.Source.Helm.ValuesObject != nil {
+		t.Errorf("Expected templated values, got %+v", template.Spec.Source.Helm)
+	}
+}
+
+// TestArgoCDProvider_GenerateApplicationSetGenerators tests the clusters and matrix generators
+func TestArgoCDProvider_GenerateApplicationSetGenerators(t *testing.T) {
+	// Load the argocd-applicationset example files
+	exampleDir := filepath.Join("..", "..", "examples", "argocd-applicationset")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	helmRelease.Spec.ClusterSelector = selector
+	provider := NewArgoCDProvider()
+
+	// The matrix generator runs every destination on every selected cluster
+	helmRelease.Spec.ArgoCD.ApplicationSet.Generator = "matrix"
+	appSet, err := provider.GenerateApplicationSet(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplicationSet with matrix generator failed: %v", err)
+	}
+
+	matrix := appSet.Spec.Generators[0].Matrix
+	if matrix == nil || len(matrix.Generators) != 2 || matrix.Generators[0].Clusters == nil || matrix.Generators[1].List == nil {
+		t.Fatalf("Expected a matrix of clusters and list generators, got %+v", appSet.Spec.Generators)
+	}
+
+	if !reflect.DeepEqual(matrix.Generators[0].Clusters.Selector, selector) {
+		t.Errorf("Expected the cluster selector to be used, got %+v", matrix.Generators[0].Clusters.Selector)
+	}
+
+	element := matrix.Generators[1].List.Elements[0]
+	if element["destination"] != "staging" || element["name"] != nil || element["server"] != nil {
+		t.Errorf("Expected list elements to only carry destination parameters, got %v", element)
+	}
+
+	if appSet.Spec.Template.Metadata.Name != "my-app-{{ .nameNormalized }}-{{ .destination }}" {
+		t.Errorf("Unexpected template name '%s'", appSet.Spec.Template.Metadata.Name)
+	}
+
+	// The clusters generator cannot carry per destination values
+	helmRelease.Spec.ArgoCD.ApplicationSet.Generator = "clusters"
+	if _, err := provider.GenerateApplicationSet(helmRelease); err == nil {
+		t.Error("Expected an error using the clusters generator with destinations")
+	}
+
+	helmRelease.Spec.Destinations = nil
+	appSet, err = provider.GenerateApplicationSet(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplicationSet with clusters generator failed: %v", err)
+	}
+
+	if appSet.Spec.Generators[0].Clusters == nil {
+		t.Fatalf("Expected a clusters generator, got %+v", appSet.Spec.Generators)
+	}
+
+	source := appSet.Spec.Template.Spec.Source
+	if source.Helm.ValuesObject["replicaCount"] != float64(2) || source.Helm.Values != "" {
+		t.Errorf("Expected release values in valuesObject, got %+v", source.Helm)
+	}
+}
+
+// TestArgoCDProvider_GenerateApplicationSetEscapesValues tests that template delimiters in the values and
+// set values reach the chart instead of being rendered by the ApplicationSet controller
+func TestArgoCDProvider_GenerateApplicationSetEscapesValues(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart:           types.ChartSpec{Name: "hello-world", Repo: "https://helm.github.io/examples"},
+		Values:          map[string]interface{}{"fullnameOverride": "{{ .Release.Name }}-web"},
+		Set:             []types.SetValue{{Name: "podLabels.release", Value: "{{ .Release.Name }}"}},
+		ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+		ArgoCD:          &types.ArgoCDSpec{ApplicationSet: &types.ApplicationSetSpec{Generator: "clusters"}},
+	}}
+	helmRelease.Name = "my-app"
+	helmRelease.Namespace = "my-system"
+
+	provider := NewArgoCDProvider()
+	appSet, err := provider.GenerateApplicationSet(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplicationSet failed: %v", err)
+	}
+
+	helm := appSet.Spec.Template.Spec.Source.Helm
+	expected := `{{ "{{" }} .Release.Name {{ "}}" }}`
+	if helm.ValuesObject["fullnameOverride"] != expected+"-web" {
+		t.Errorf("Expected the values to be escaped, got %v", helm.ValuesObject)
+	}
+	if helm.Parameters[0].Value != expected {
+		t.Errorf("Expected the set values to be escaped, got %+v", helm.Parameters)
+	}
+
+	// The HelmRelease values are left untouched
+	if helmRelease.Spec.Values["fullnameOverride"] != "{{ .Release.Name }}-web" {
+		t.Error("Expected the HelmRelease values not to be modified")
+	}
+
+	// Applications are not templates
+	app, err := provider.GenerateApplication(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplication failed: %v", err)
+	}
+	if app.Spec.Source.Helm.Parameters[0].Value != "{{ .Release.Name }}" {
+		t.Errorf("Expected the Application set values unchanged, got %+v", app.Spec.Source.Helm.Parameters)
+	}
+}
+
+// TestArgoCDProvider_GenerateApplicationSyncPolicy tests the mapping of the portable sync settings
+func TestArgoCDProvider_GenerateApplicationSyncPolicy(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Sync = &types.SyncSpec{
+		Automated:       true,
+		Prune:           true,
+		SelfHeal:        true,
+		Retry:           &types.RetrySpec{Limit: 5, Backoff: &types.BackoffSpec{Duration: "5s", Factor: 2, MaxDuration: "3m"}},
+		CreateNamespace: true,
+		ServerSideApply: true,
+		IgnoreCRDDrift:  true,
+	}
+
+	provider := NewArgoCDProvider()
+	app, err := provider.GenerateApplication(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplication failed: %v", err)
+	}
+
+	syncPolicy := app.Spec.SyncPolicy
+	if syncPolicy.Automated == nil || !syncPolicy.Automated.Prune || !syncPolicy.Automated.SelfHeal {
+		t.Errorf("Expected automated sync with prune and self heal, got %+v", syncPolicy.Automated)
+	}
+
+	if syncPolicy.Retry == nil || syncPolicy.Retry.Limit != 5 || syncPolicy.Retry.Backoff == nil || *syncPolicy.Retry.Backoff.Factor != 2 {
+		t.Errorf("Unexpected retry strategy %+v", syncPolicy.Retry)
+	}
+
+	expectedOptions := []string{"CreateNamespace=true", "ServerSideApply=true", "RespectIgnoreDifferences=true"}
+	if !reflect.DeepEqual(syncPolicy.SyncOptions, expectedOptions) {
+		t.Errorf("Expected sync options %v, got %v", expectedOptions, syncPolicy.SyncOptions)
+	}
+
+	if len(app.Spec.IgnoreDifferences) != 1 || app.Spec.IgnoreDifferences[0].Kind != "CustomResourceDefinition" {
+		t.Errorf("Expected CRD differences to be ignored, got %+v", app.Spec.IgnoreDifferences)
+	}
+}
+
+// TestArgoCDProvider_GenerateAppProject tests the least privilege AppProject generated for a release
+func TestArgoCDProvider_GenerateAppProject(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewArgoCDProvider()
+	if provider.UsesAppProject(helmRelease) {
+		t.Fatal("Expected no AppProject without spec.argocd.appProject")
+	}
+
+	helmRelease.Spec.ArgoCD = &types.ArgoCDSpec{AppProject: &types.AppProjectSpec{
+		InstallsCRDs:     true,
+		ClusterResources: []types.GroupKind{{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}},
+	}}
+	helmRelease.Spec.ValuesFiles = []types.ValuesFile{{Path: "envs/prod.yaml", Repo: "https://github.com/example/config.git"}}
+
+	appProject, err := provider.GenerateAppProject(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateAppProject failed: %v", err)
+	}
+
+	if appProject.Kind != "AppProject" || appProject.Name != "my-app" || appProject.Namespace != "argocd" {
+		t.Errorf("Unexpected AppProject %s %s/%s", appProject.Kind, appProject.Namespace, appProject.Name)
+	}
+
+	expectedRepos := []string{"https://helm.github.io/examples", "https://github.com/example/config.git"}
+	if !reflect.DeepEqual(appProject.Spec.SourceRepos, expectedRepos) {
+		t.Errorf("Expected source repos %v, got %v", expectedRepos, appProject.Spec.SourceRepos)
+	}
+
+	expectedDestinations := []ArgoCDDestination{{Server: DefaultServer, Namespace: "my-system"}}
+	if !reflect.DeepEqual(appProject.Spec.Destinations, expectedDestinations) {
+		t.Errorf("Expected destinations %+v, got %+v", expectedDestinations, appProject.Spec.Destinations)
+	}
+
+	expectedWhitelist := []ArgoCDGroupKind{
+		{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"},
+		{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+	}
+	if !reflect.DeepEqual(appProject.Spec.ClusterResourceWhitelist, expectedWhitelist) {
+		t.Errorf("Expected cluster resources %+v, got %+v", expectedWhitelist, appProject.Spec.ClusterResourceWhitelist)
+	}
+
+	// The Application is scoped to the generated project
+	app, err := provider.GenerateApplication(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplication failed: %v", err)
+	}
+	if app.Spec.Project != "my-app" {
+		t.Errorf("Expected project 'my-app', got '%s'", app.Spec.Project)
+	}
+
+	// Clusters found by the matrix generator are only known to ArgoCD
+	helmRelease.Spec.ArgoCD.AppProject.Name = "team-a"
+	helmRelease.Spec.ArgoCD.ApplicationSet = &types.ApplicationSetSpec{Generator: types.GeneratorMatrix}
+	helmRelease.Spec.Destinations = []types.Destination{{Name: "eu"}, {Name: "us", Namespace: "us-system"}}
+
+	appProject, err = provider.GenerateAppProject(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateAppProject failed: %v", err)
+	}
+
+	expectedDestinations = []ArgoCDDestination{{Server: "*", Namespace: "my-system"}, {Server: "*", Namespace: "us-system"}}
+	if appProject.Name != "team-a" || !reflect.DeepEqual(appProject.Spec.Destinations, expectedDestinations) {
+		t.Errorf("Expected project 'team-a' with destinations %+v, got '%s' with %+v", expectedDestinations, appProject.Name, appProject.Spec.Destinations)
+	}
+}
+
+// TestArgoCDProvider_GenerateRepositorySecret tests the repository Secret generated for spec.chart.auth
+func TestArgoCDProvider_GenerateRepositorySecret(t *testing.T) {
+	// Load the argocd example files
+	exampleDir := filepath.Join("..", "..", "examples", "argocd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewArgoCDProvider()
+	if provider.UsesRepositorySecret(helmRelease) {
+		t.Fatal("Expected no repository Secret without spec.chart.auth")
+	}
+
+	helmRelease.Spec.Chart.Repo = "oci://ghcr.io/example/charts"
+	helmRelease.Spec.Chart.Auth = &types.ChartAuthSpec{SecretName: "repo-auth", PassCredentials: true, InsecureSkipTLSVerify: true}
+
+	secret, err := provider.GenerateRepositorySecret(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateRepositorySecret failed: %v", err)
+	}
+
+	if secret.Kind != "Secret" || secret.Namespace != "argocd" || secret.Labels[SecretTypeLabel] != "repository" {
+		t.Errorf("Unexpected Secret %s %s/%s with labels %v", secret.Kind, secret.Namespace, secret.Name, secret.Labels)
+	}
+
+	expected := map[string]string{"type": "helm", "url": "ghcr.io/example/charts", "enableOCI": "true", "insecure": "true"}
+	if !reflect.DeepEqual(secret.StringData, expected) {
+		t.Errorf("Expected stringData %v, got %v", expected, secret.StringData)
+	}
+
+	// The name only depends on the repository
+	other, err := provider.GenerateRepositorySecret(&types.HelmRelease{Spec: types.HelmReleaseSpec{Chart: types.ChartSpec{
+		Repo: "oci://ghcr.io/example/charts",
+		Auth: &types.ChartAuthSpec{},
+	}}})
+	if err != nil {
+		t.Fatalf("GenerateRepositorySecret failed: %v", err)
+	}
+	if other.Name != secret.Name {
+		t.Errorf("Expected releases of the same repository to share '%s', got '%s'", secret.Name, other.Name)
+	}
+
+	app, err := provider.GenerateApplication(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApplication failed: %v", err)
+	}
+	if app.Spec.Source.Helm == nil || !app.Spec.Source.Helm.PassCredentials {
+		t.Errorf("Expected the Application to pass credentials, got %+v", app.Spec.Source.Helm)
+	}
+}
@@ -0,0 +1,166 @@
+package capi
+
+import (
+	"fmt"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultClusterNamespace is where Cluster API Clusters are created unless told otherwise
+const DefaultClusterNamespace = "default"
+
+// CAPIHelmChartProxy represents a Cluster API add-on provider HelmChartProxy resource
+type CAPIHelmChartProxy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CAPIHelmChartProxySpec `json:"spec,omitempty"`
+}
+
+// CAPIHelmChartProxySpec defines the chart installed on every Cluster matching the selector
+type CAPIHelmChartProxySpec struct {
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+	RepoURL         string               `json:"repoURL"`
+	ChartName       string               `json:"chartName"`
+	Version         string               `json:"version,omitempty"`
+	ReleaseName     string               `json:"releaseName,omitempty"`
+	Namespace       string               `json:"namespace,omitempty"`
+	ValuesTemplate  string               `json:"valuesTemplate,omitempty"`
+	Options         *CAPIHelmOptions     `json:"options,omitempty"`
+	TLSConfig       *CAPITLSConfig       `json:"tlsConfig,omitempty"`
+}
+
+// CAPIHelmOptions defines how the chart is installed and upgraded
+type CAPIHelmOptions struct {
+	Timeout string                  `json:"timeout,omitempty"`
+	Install *CAPIHelmInstallOptions `json:"install,omitempty"`
+	Upgrade *CAPIHelmUpgradeOptions `json:"upgrade,omitempty"`
+}
+
+// CAPIHelmInstallOptions defines how the chart is installed
+type CAPIHelmInstallOptions struct {
+	CreateNamespace bool `json:"createNamespace,omitempty"`
+}
+
+// CAPIHelmUpgradeOptions defines how the chart is upgraded
+type CAPIHelmUpgradeOptions struct {
+	MaxHistory int `json:"maxHistory,omitempty"`
+}
+
+// CAPITLSConfig defines how the chart repository certificate is verified
+type CAPITLSConfig struct {
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// CAPIProvider handles the transformation of HelmRelease to Cluster API HelmChartProxy
+type CAPIProvider struct{}
+
+// NewCAPIProvider creates a new Cluster API provider instance
+func NewCAPIProvider() *CAPIProvider {
+	return &CAPIProvider{}
+}
+
+// GenerateHelmChartProxy creates a HelmChartProxy installing the chart on every Cluster API Cluster
+// matching the cluster selector of the HelmRelease. The HelmChartProxy lives next to the Clusters, the
+// namespace of the HelmRelease is where the chart is installed on each of them.
+func (p *CAPIProvider) GenerateHelmChartProxy(helmRelease *types.HelmRelease) (*CAPIHelmChartProxy, error) {
+	if helmRelease.Spec.ClusterSelector == nil {
+		return nil, fmt.Errorf("spec.clusterSelector is required to select the Cluster API clusters")
+	}
+	if helmRelease.Spec.Chart.Repo == "" {
+		return nil, fmt.Errorf("spec.chart.repo is required to generate a HelmChartProxy")
+	}
+
+	valuesTemplate, err := valuesTemplate(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterNamespace := DefaultClusterNamespace
+	if capi := helmRelease.Spec.CAPI; capi != nil && capi.Namespace != "" {
+		clusterNamespace = capi.Namespace
+	}
+
+	proxy := &CAPIHelmChartProxy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "addons.cluster.x-k8s.io/v1alpha1",
+			Kind:       "HelmChartProxy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.QualifiedName(helmRelease.ObjectMeta.Namespace, helmRelease.ObjectMeta.Name),
+			Namespace: clusterNamespace,
+		},
+		Spec: CAPIHelmChartProxySpec{
+			ClusterSelector: *helmRelease.Spec.ClusterSelector,
+			RepoURL:         helmRelease.Spec.Chart.Repo,
+			ChartName:       helmRelease.Spec.Chart.Name,
+			Version:         helmRelease.Spec.Chart.Version,
+			ReleaseName:     helmRelease.ObjectMeta.Name,
+			Namespace:       helmRelease.ObjectMeta.Namespace,
+			ValuesTemplate:  valuesTemplate,
+		},
+	}
+
+	options := &CAPIHelmOptions{}
+	applySyncSpec(options, helmRelease.Spec.Sync)
+	applyLifecycleSpec(options, helmRelease.Spec.Lifecycle)
+	if *options != (CAPIHelmOptions{}) {
+		proxy.Spec.Options = options
+	}
+
+	if auth := helmRelease.Spec.Chart.Auth; auth != nil && auth.InsecureSkipTLSVerify {
+		proxy.Spec.TLSConfig = &CAPITLSConfig{InsecureSkipTLSVerify: true}
+	}
+
+	return proxy, nil
+}
+
+// valuesTemplate renders the values, with the literal set values merged in, as the Go template the
+// add-on provider renders for each cluster. Template delimiters already in the values are escaped so they
+// reach the chart as they are, unless the values are meant to be templates.
+func valuesTemplate(helmRelease *types.HelmRelease) (string, error) {
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return "", err
+	}
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return "", err
+	}
+	if len(chartValues) == 0 {
+		return "", nil
+	}
+
+	valuesBytes, err := yaml.Marshal(chartValues)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal values: %w", err)
+	}
+
+	if capi := helmRelease.Spec.CAPI; capi != nil && capi.RenderTemplates {
+		return string(valuesBytes), nil
+	}
+	return values.EscapeTemplate(string(valuesBytes)), nil
+}
+
+// applySyncSpec maps the portable sync settings which have an add-on provider equivalent. The add-on
+// provider always reconciles the selected clusters, the other settings have no equivalent.
+func applySyncSpec(options *CAPIHelmOptions, sync *types.SyncSpec) {
+	if sync == nil || !sync.CreateNamespace {
+		return
+	}
+
+	options.Install = &CAPIHelmInstallOptions{CreateNamespace: true}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have an add-on provider equivalent
+func applyLifecycleSpec(options *CAPIHelmOptions, lifecycle *types.LifecycleSpec) {
+	if lifecycle == nil {
+		return
+	}
+
+	options.Timeout = lifecycle.Timeout
+	if lifecycle.MaxHistory != nil {
+		options.Upgrade = &CAPIHelmUpgradeOptions{MaxHistory: *lifecycle.MaxHistory}
+	}
+}
@@ -0,0 +1,112 @@
+package capi
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewCAPIProvider(t *testing.T) {
+	provider := NewCAPIProvider()
+	if provider == nil {
+		t.Error("NewCAPIProvider returned nil")
+	}
+}
+
+// TestCAPIProvider_GenerateHelmChartProxyFromExample tests the Cluster API provider using example files
+func TestCAPIProvider_GenerateHelmChartProxyFromExample(t *testing.T) {
+	// Load the capi example files
+	exampleDir := filepath.Join("..", "..", "examples", "capi")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Parse the HelmRelease from the example
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewCAPIProvider()
+	proxy, err := provider.GenerateHelmChartProxy(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmChartProxy failed: %v", err)
+	}
+
+	if proxy.APIVersion != "addons.cluster.x-k8s.io/v1alpha1" || proxy.Kind != "HelmChartProxy" {
+		t.Errorf("Expected an addons.cluster.x-k8s.io/v1alpha1 HelmChartProxy, got %s %s", proxy.APIVersion, proxy.Kind)
+	}
+
+	// The HelmChartProxy lives next to the Clusters, the chart is installed in the release namespace
+	if proxy.Name != "my-system-my-app-c364f582" || proxy.Namespace != DefaultClusterNamespace || proxy.Spec.Namespace != "my-system" {
+		t.Errorf("Unexpected HelmChartProxy %s/%s installing in %s", proxy.Namespace, proxy.Name, proxy.Spec.Namespace)
+	}
+
+	if proxy.Spec.RepoURL != "https://helm.github.io/examples" || proxy.Spec.ChartName != "hello-world" ||
+		proxy.Spec.Version != "0.1.0" || proxy.Spec.ReleaseName != "my-app" {
+		t.Errorf("Unexpected chart %+v", proxy.Spec)
+	}
+
+	expectedSelector := metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}}
+	if !reflect.DeepEqual(proxy.Spec.ClusterSelector, expectedSelector) {
+		t.Errorf("Expected cluster selector %+v, got %+v", expectedSelector, proxy.Spec.ClusterSelector)
+	}
+
+	expectedValues := "podAnnotations:\n  greeting: Hello {{ \"{{\" }} .Values.name {{ \"}}\" }}\nreplicaCount: 2\n"
+	if proxy.Spec.ValuesTemplate != expectedValues {
+		t.Errorf("Expected values template %q, got %q", expectedValues, proxy.Spec.ValuesTemplate)
+	}
+
+	if proxy.Spec.Options == nil || proxy.Spec.Options.Install == nil || !proxy.Spec.Options.Install.CreateNamespace {
+		t.Errorf("Expected the namespace to be created, got %+v", proxy.Spec.Options)
+	}
+}
+
+// TestCAPIProvider_GenerateHelmChartProxySettings tests the templating, lifecycle and validation
+func TestCAPIProvider_GenerateHelmChartProxySettings(t *testing.T) {
+	maxHistory := 5
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart:     types.ChartSpec{Name: "cilium", Repo: "https://helm.cilium.io", Auth: &types.ChartAuthSpec{InsecureSkipTLSVerify: true}},
+		Values:    map[string]interface{}{"cluster": map[string]interface{}{"name": "{{ .Cluster.metadata.name }}"}},
+		Set:       []types.SetValue{{Name: "ipam.mode", Value: "kubernetes"}},
+		Lifecycle: &types.LifecycleSpec{Timeout: "10m", MaxHistory: &maxHistory},
+		CAPI:      &types.CAPISpec{Namespace: "clusters", RenderTemplates: true},
+	}}
+	helmRelease.Name = "cilium"
+	helmRelease.Namespace = "kube-system"
+
+	provider := NewCAPIProvider()
+	if _, err := provider.GenerateHelmChartProxy(helmRelease); err == nil {
+		t.Error("Expected an error without spec.clusterSelector")
+	}
+
+	helmRelease.Spec.ClusterSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"cni": "cilium"}}
+	proxy, err := provider.GenerateHelmChartProxy(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmChartProxy failed: %v", err)
+	}
+
+	if proxy.Namespace != "clusters" {
+		t.Errorf("Expected the HelmChartProxy in the clusters namespace, got '%s'", proxy.Namespace)
+	}
+
+	// Templates are left for the add-on provider to render per cluster
+	expectedValues := "cluster:\n  name: '{{ .Cluster.metadata.name }}'\nipam:\n  mode: kubernetes\n"
+	if proxy.Spec.ValuesTemplate != expectedValues {
+		t.Errorf("Expected values template %q, got %q", expectedValues, proxy.Spec.ValuesTemplate)
+	}
+
+	expectedOptions := &CAPIHelmOptions{Timeout: "10m", Upgrade: &CAPIHelmUpgradeOptions{MaxHistory: 5}}
+	if !reflect.DeepEqual(proxy.Spec.Options, expectedOptions) {
+		t.Errorf("Expected options %+v, got %+v", expectedOptions, proxy.Spec.Options)
+	}
+
+	if proxy.Spec.TLSConfig == nil || !proxy.Spec.TLSConfig.InsecureSkipTLSVerify {
+		t.Errorf("Expected the repository certificate not to be verified, got %+v", proxy.Spec.TLSConfig)
+	}
+}
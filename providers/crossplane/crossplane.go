@@ -1,10 +1,25 @@
 package crossplane
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// DefaultProviderConfig is the ProviderConfig used by a Release unless told otherwise
+	DefaultProviderConfig = "default"
+	// PausedAnnotation stops Crossplane from reconciling a resource
+	PausedAnnotation = "crossplane.io/paused"
+	// DependsOnAnnotation lists the releases a Release depends on as comma separated namespace/name pairs.
+	// Crossplane has no dependency ordering of its own, composition functions such as function-sequencer
+	// can order the Releases with it.
+	DependsOnAnnotation = "krm.kubed.io/depends-on"
+)
+
 // CrossplaneRelease represents a Crossplane Helm Release resource
 type CrossplaneRelease struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -14,8 +29,99 @@ type CrossplaneRelease struct {
 
 // CrossplaneReleaseSpec defines the desired state of Crossplane Release
 type CrossplaneReleaseSpec struct {
-	// For phase 1, we'll keep this minimal
-	// Full spec will be added in future phases
+	ForProvider                CrossplaneReleaseParameters  `json:"forProvider"`
+	ConnectionDetails          []CrossplaneConnectionDetail `json:"connectionDetails,omitempty"`
+	ProviderConfigRef          *CrossplaneReference         `json:"providerConfigRef,omitempty"`
+	WriteConnectionSecretToRef *CrossplaneSecretReference   `json:"writeConnectionSecretToRef,omitempty"`
+}
+
+// CrossplaneConnectionDetail publishes a field of a release resource in the connection secret
+type CrossplaneConnectionDetail struct {
+	APIVersion             string `json:"apiVersion"`
+	Kind                   string `json:"kind"`
+	Name                   string `json:"name"`
+	Namespace              string `json:"namespace,omitempty"`
+	FieldPath              string `json:"fieldPath"`
+	ToConnectionSecretKey  string `json:"toConnectionSecretKey"`
+	SkipPartOfReleaseCheck bool   `json:"skipPartOfReleaseCheck,omitempty"`
+}
+
+// CrossplaneSecretReference references a Secret in a namespace
+type CrossplaneSecretReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// CrossplaneSecretKeySelector references a key of a Secret in a namespace
+type CrossplaneSecretKeySelector struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+// CrossplaneProviderConfig represents a provider-helm ProviderConfig resource
+type CrossplaneProviderConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CrossplaneProviderConfigSpec `json:"spec,omitempty"`
+}
+
+// CrossplaneProviderConfigSpec defines how provider-helm connects to a cluster
+type CrossplaneProviderConfigSpec struct {
+	Credentials CrossplaneProviderCredentials `json:"credentials"`
+}
+
+// CrossplaneProviderCredentials defines where the cluster credentials come from
+type CrossplaneProviderCredentials struct {
+	Source    string                       `json:"source"`
+	SecretRef *CrossplaneSecretKeySelector `json:"secretRef,omitempty"`
+}
+
+// CrossplaneReleaseParameters defines the Helm release managed by provider-helm
+type CrossplaneReleaseParameters struct {
+	Chart                 CrossplaneChartSpec         `json:"chart"`
+	Namespace             string                      `json:"namespace"`
+	RollbackLimit         *int                        `json:"rollbackLimit,omitempty"`
+	Wait                  bool                        `json:"wait,omitempty"`
+	WaitTimeout           string                      `json:"waitTimeout,omitempty"`
+	InsecureSkipTLSVerify bool                        `json:"insecureSkipTLSVerify,omitempty"`
+	Values                map[string]interface{}      `json:"values,omitempty"`
+	Set                   []CrossplaneSetVal          `json:"set,omitempty"`
+	PatchesFrom           []CrossplaneValueFromSource `json:"patchesFrom,omitempty"`
+}
+
+// CrossplaneSetVal overrides a single value like helm --set, either literally or from a Secret or ConfigMap
+type CrossplaneSetVal struct {
+	Name      string                     `json:"name"`
+	Value     string                     `json:"value,omitempty"`
+	ValueFrom *CrossplaneValueFromSource `json:"valueFrom,omitempty"`
+}
+
+// CrossplaneValueFromSource selects the Secret or ConfigMap key a value is read from
+type CrossplaneValueFromSource struct {
+	ConfigMapKeyRef *CrossplaneDataKeySelector `json:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *CrossplaneDataKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// CrossplaneDataKeySelector selects a key of a Secret or ConfigMap in a namespace
+type CrossplaneDataKeySelector struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Optional  bool   `json:"optional,omitempty"`
+}
+
+// CrossplaneChartSpec defines the chart to install
+type CrossplaneChartSpec struct {
+	Name          string                     `json:"name"`
+	Repository    string                     `json:"repository,omitempty"`
+	Version       string                     `json:"version,omitempty"`
+	PullSecretRef *CrossplaneSecretReference `json:"pullSecretRef,omitempty"`
+}
+
+// CrossplaneReference references another Crossplane resource by name
+type CrossplaneReference struct {
+	Name string `json:"name"`
 }
 
 // CrossplaneProvider handles the transformation of HelmRelease to Crossplane Release
@@ -39,9 +145,261 @@ func (p *CrossplaneProvider) GenerateRelease(helmRelease *types.HelmRelease) (*C
 			// Crossplane Helm Release is cluster-scoped, so no namespace
 		},
 		Spec: CrossplaneReleaseSpec{
-			// Minimal spec for phase 1
+			ForProvider: CrossplaneReleaseParameters{
+				Chart: CrossplaneChartSpec{
+					Name:       helmRelease.Spec.Chart.Name,
+					Repository: helmRelease.Spec.Chart.Repo,
+					Version:    helmRelease.Spec.Chart.Version,
+				},
+				Namespace: helmRelease.ObjectMeta.Namespace,
+				Values:    helmRelease.Spec.Values,
+			},
+			ProviderConfigRef: &CrossplaneReference{Name: providerConfigName(helmRelease)},
 		},
 	}
 
+	if err := applyConnectionSpec(&release.Spec, helmRelease); err != nil {
+		return nil, err
+	}
+
+	// provider-helm pulls charts with the username and password of a Secret in the release namespace
+	if auth := helmRelease.Spec.Chart.Auth; auth != nil {
+		if auth.SecretName != "" {
+			release.Spec.ForProvider.Chart.PullSecretRef = &CrossplaneSecretReference{
+				Name:      auth.SecretName,
+				Namespace: helmRelease.ObjectMeta.Namespace,
+			}
+		}
+		release.Spec.ForProvider.InsecureSkipTLSVerify = auth.InsecureSkipTLSVerify
+	}
+
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return nil, err
+	}
+	for _, set := range helmRelease.Spec.Set {
+		release.Spec.ForProvider.Set = append(release.Spec.ForProvider.Set, setVal(set, helmRelease))
+	}
+
+	if crossplane := helmRelease.Spec.Crossplane; crossplane != nil {
+		for _, ref := range crossplane.PatchesFrom {
+			patch, err := patchFrom(ref, helmRelease)
+			if err != nil {
+				return nil, err
+			}
+			release.Spec.ForProvider.PatchesFrom = append(release.Spec.ForProvider.PatchesFrom, patch)
+		}
+	}
+
+	applySyncSpec(&release.Spec.ForProvider, helmRelease.Spec.Sync)
+	applyLifecycleSpec(release, helmRelease.Spec.Lifecycle)
+
+	if len(helmRelease.Spec.DependsOn) > 0 {
+		dependencies := make([]string, 0, len(helmRelease.Spec.DependsOn))
+		for _, dependency := range helmRelease.Spec.DependsOn {
+			namespace := dependency.Namespace
+			if namespace == "" {
+				namespace = helmRelease.ObjectMeta.Namespace
+			}
+			dependencies = append(dependencies, namespace+"/"+dependency.Name)
+		}
+		setAnnotation(release, DependsOnAnnotation, strings.Join(dependencies, ","))
+	}
+
 	return release, nil
 }
+
+// UsesProviderConfig reports whether a ProviderConfig is generated for the HelmRelease
+func (p *CrossplaneProvider) UsesProviderConfig(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.Crossplane != nil && helmRelease.Spec.Crossplane.ProviderConfig != nil
+}
+
+// GenerateProviderConfig creates the provider-helm ProviderConfig a Release uses to reach its cluster,
+// either through the identity of the provider pod or a kubeconfig stored in a Secret
+func (p *CrossplaneProvider) GenerateProviderConfig(helmRelease *types.HelmRelease) (*CrossplaneProviderConfig, error) {
+	if !p.UsesProviderConfig(helmRelease) {
+		return nil, fmt.Errorf("spec.crossplane.providerConfig is not set")
+	}
+	config := helmRelease.Spec.Crossplane.ProviderConfig
+
+	credentials := CrossplaneProviderCredentials{Source: config.Source}
+	switch config.Source {
+	case "", types.CredentialsInjectedIdentity:
+		credentials.Source = types.CredentialsInjectedIdentity
+		if config.SecretRef != nil {
+			return nil, fmt.Errorf("the InjectedIdentity credentials source does not use a secretRef")
+		}
+	case types.CredentialsSecret:
+		if config.SecretRef == nil || config.SecretRef.Name == "" || config.SecretRef.Key == "" {
+			return nil, fmt.Errorf("the Secret credentials source needs a secretRef with a name and key")
+		}
+		credentials.SecretRef = &CrossplaneSecretKeySelector{
+			Name:      config.SecretRef.Name,
+			Namespace: namespaceOrDefault(config.SecretRef.Namespace, helmRelease),
+			Key:       config.SecretRef.Key,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported credentials source: %s", config.Source)
+	}
+
+	providerConfig := &CrossplaneProviderConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "helm.crossplane.io/v1beta1",
+			Kind:       "ProviderConfig",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: providerConfigName(helmRelease),
+			// ProviderConfig is cluster-scoped, so no namespace
+		},
+		Spec: CrossplaneProviderConfigSpec{Credentials: credentials},
+	}
+
+	return providerConfig, nil
+}
+
+// providerConfigName returns the ProviderConfig a Release uses. A generated ProviderConfig is cluster scoped,
+// it is named after the namespace and name of the release unless a name is given.
+func providerConfigName(helmRelease *types.HelmRelease) string {
+	spec := helmRelease.Spec.Crossplane
+	switch {
+	case spec != nil && spec.ProviderConfigRef != "":
+		return spec.ProviderConfigRef
+	case spec != nil && spec.ProviderConfig != nil:
+		return types.QualifiedName(helmRelease.ObjectMeta.Namespace, helmRelease.ObjectMeta.Name)
+	default:
+		return DefaultProviderConfig
+	}
+}
+
+// applyConnectionSpec publishes the connection details of the release resources in a connection secret
+func applyConnectionSpec(spec *CrossplaneReleaseSpec, helmRelease *types.HelmRelease) error {
+	crossplane := helmRelease.Spec.Crossplane
+	if crossplane == nil {
+		return nil
+	}
+
+	for _, detail := range crossplane.ConnectionDetails {
+		if detail.Kind == "" || detail.Name == "" || detail.FieldPath == "" || detail.ToConnectionSecretKey == "" {
+			return fmt.Errorf("connection details need a kind, name, fieldPath and toConnectionSecretKey")
+		}
+		spec.ConnectionDetails = append(spec.ConnectionDetails, CrossplaneConnectionDetail{
+			APIVersion:             detail.APIVersion,
+			Kind:                   detail.Kind,
+			Name:                   detail.Name,
+			Namespace:              namespaceOrDefault(detail.Namespace, helmRelease),
+			FieldPath:              detail.FieldPath,
+			ToConnectionSecretKey:  detail.ToConnectionSecretKey,
+			SkipPartOfReleaseCheck: detail.SkipPartOfReleaseCheck,
+		})
+	}
+
+	if ref := crossplane.WriteConnectionSecretToRef; ref != nil {
+		if ref.Name == "" {
+			return fmt.Errorf("writeConnectionSecretToRef needs a name")
+		}
+		spec.WriteConnectionSecretToRef = &CrossplaneSecretReference{
+			Name:      ref.Name,
+			Namespace: namespaceOrDefault(ref.Namespace, helmRelease),
+		}
+	} else if len(spec.ConnectionDetails) > 0 {
+		return fmt.Errorf("connection details need writeConnectionSecretToRef")
+	}
+
+	return nil
+}
+
+// setVal converts a set value, the Secret or ConfigMap is read from the namespace of the release
+func setVal(set types.SetValue, helmRelease *types.HelmRelease) CrossplaneSetVal {
+	val := CrossplaneSetVal{Name: set.Name, Value: set.Value}
+	if set.ValueFrom == nil {
+		return val
+	}
+
+	ref := values.KeyRef(set.ValueFrom)
+	selector := &CrossplaneDataKeySelector{
+		Name:      ref.Name,
+		Namespace: helmRelease.ObjectMeta.Namespace,
+		Key:       ref.Key,
+		Optional:  ref.Optional,
+	}
+	val.ValueFrom = &CrossplaneValueFromSource{}
+	if set.ValueFrom.SecretKeyRef != nil {
+		val.ValueFrom.SecretKeyRef = selector
+	} else {
+		val.ValueFrom.ConfigMapKeyRef = selector
+	}
+	return val
+}
+
+// patchFrom maps a reference to a Secret or ConfigMap key in the namespace of the release holding patches
+func patchFrom(ref types.ValuesReference, helmRelease *types.HelmRelease) (CrossplaneValueFromSource, error) {
+	if ref.Name == "" || ref.Key == "" {
+		return CrossplaneValueFromSource{}, fmt.Errorf("patch reference without a name and key")
+	}
+
+	selector := &CrossplaneDataKeySelector{
+		Name:      ref.Name,
+		Namespace: helmRelease.ObjectMeta.Namespace,
+		Key:       ref.Key,
+	}
+
+	switch ref.Kind {
+	case "Secret":
+		return CrossplaneValueFromSource{SecretKeyRef: selector}, nil
+	case "ConfigMap":
+		return CrossplaneValueFromSource{ConfigMapKeyRef: selector}, nil
+	default:
+		return CrossplaneValueFromSource{}, fmt.Errorf("unsupported patch reference kind: %s", ref.Kind)
+	}
+}
+
+// namespaceOrDefault returns the namespace, or the namespace of the HelmRelease when it is empty
+func namespaceOrDefault(namespace string, helmRelease *types.HelmRelease) string {
+	if namespace == "" {
+		return helmRelease.ObjectMeta.Namespace
+	}
+	return namespace
+}
+
+// applySyncSpec maps the portable sync settings onto the closest provider-helm equivalents. Crossplane
+// always reconciles and provider-helm creates the namespace by default, so only retries need a mapping.
+func applySyncSpec(params *CrossplaneReleaseParameters, sync *types.SyncSpec) {
+	if sync == nil {
+		return
+	}
+
+	if sync.Retry != nil && sync.Retry.Limit > 0 {
+		limit := sync.Retry.Limit
+		params.RollbackLimit = &limit
+	}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have a provider-helm equivalent. Upgrade
+// retries become rollbacks, a timeout makes provider-helm wait for the release and a suspended release
+// is paused.
+func applyLifecycleSpec(release *CrossplaneRelease, lifecycle *types.LifecycleSpec) {
+	if lifecycle == nil {
+		return
+	}
+
+	if lifecycle.Upgrade != nil && lifecycle.Upgrade.Retries > 0 {
+		limit := lifecycle.Upgrade.Retries
+		release.Spec.ForProvider.RollbackLimit = &limit
+	}
+
+	if lifecycle.Timeout != "" {
+		release.Spec.ForProvider.Wait = true
+		release.Spec.ForProvider.WaitTimeout = lifecycle.Timeout
+	}
+
+	if lifecycle.Suspend {
+		setAnnotation(release, PausedAnnotation, "true")
+	}
+}
+
+// setAnnotation sets an annotation on the Release
+func setAnnotation(release *CrossplaneRelease, key, value string) {
+	if release.ObjectMeta.Annotations == nil {
+		release.ObjectMeta.Annotations = map[string]string{}
+	}
+	release.ObjectMeta.Annotations[key] = value
+}
@@ -2,8 +2,10 @@ package crossplane
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
 	"github.com/kubed-io/krm-helm-fn/testutil"
 )
 
@@ -58,3 +60,269 @@ func TestCrossplaneProvider_GenerateReleaseFromExample(t *testing.T) {
 	// For crossplane provider in hello world state, we just check basic structure
 	// Future tests will validate the full spec content
 }
+
+// TestCrossplaneProvider_GenerateReleaseSpec tests the forProvider parameters and the mapping of the portable sync settings
+func TestCrossplaneProvider_GenerateReleaseSpec(t *testing.T) {
+	// Load the crossplane example files
+	exampleDir := filepath.Join("..", "..", "examples", "crossplane")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Sync = &types.SyncSpec{Retry: &types.RetrySpec{Limit: 4}}
+
+	provider := NewCrossplaneProvider()
+	release, err := provider.GenerateRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateRelease failed: %v", err)
+	}
+
+	params := release.Spec.ForProvider
+	if params.Chart.Name != "hello-world" || params.Chart.Repository != "https://helm.github.io/examples" || params.Chart.Version != "0.1.0" {
+		t.Errorf("Unexpected chart %+v", params.Chart)
+	}
+
+	if params.Namespace != "my-system" {
+		t.Errorf("Expected namespace 'my-system', got '%s'", params.Namespace)
+	}
+
+	if params.RollbackLimit == nil || *params.RollbackLimit != 4 {
+		t.Errorf("Expected rollback limit 4, got %v", params.RollbackLimit)
+	}
+
+	if release.Spec.ProviderConfigRef == nil || release.Spec.ProviderConfigRef.Name != "default" {
+		t.Errorf("Expected the default ProviderConfig, got %+v", release.Spec.ProviderConfigRef)
+	}
+}
+
+// TestCrossplaneProvider_GenerateReleaseDependsOn tests the ordering hint of a release with dependencies
+func TestCrossplaneProvider_GenerateReleaseDependsOn(t *testing.T) {
+	// Load the crossplane example files
+	exampleDir := filepath.Join("..", "..", "examples", "crossplane")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.DependsOn = []types.ReleaseReference{{Name: "cert-manager", Namespace: "cert-manager"}, {Name: "ingress"}}
+	helmRelease.Spec.Lifecycle = &types.LifecycleSpec{Suspend: true}
+
+	provider := NewCrossplaneProvider()
+	release, err := provider.GenerateRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateRelease failed: %v", err)
+	}
+
+	if got := release.Annotations[DependsOnAnnotation]; got != "cert-manager/cert-manager,my-system/ingress" {
+		t.Errorf("Expected dependencies 'cert-manager/cert-manager,my-system/ingress', got '%s'", got)
+	}
+
+	if release.Annotations[PausedAnnotation] != "true" {
+		t.Error("Expected the suspended release to be paused")
+	}
+}
+
+// TestCrossplaneProvider_GenerateProviderConfig tests the generated ProviderConfig and the connection details
+func TestCrossplaneProvider_GenerateProviderConfig(t *testing.T) {
+	// Load the crossplane example files
+	exampleDir := filepath.Join("..", "..", "examples", "crossplane")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewCrossplaneProvider()
+	if provider.UsesProviderConfig(helmRelease) {
+		t.Fatal("Expected no ProviderConfig without spec.crossplane.providerConfig")
+	}
+
+	helmRelease.Spec.Crossplane = &types.CrossplaneSpec{
+		ProviderConfig: &types.ProviderConfigSpec{
+			Source:    types.CredentialsSecret,
+			SecretRef: &types.SecretKeyReference{Name: "cluster-kubeconfig", Namespace: "crossplane-system", Key: "kubeconfig"},
+		},
+		ConnectionDetails: []types.ConnectionDetail{{
+			APIVersion:            "v1",
+			Kind:                  "Service",
+			Name:                  "my-app",
+			FieldPath:             "spec.clusterIP",
+			ToConnectionSecretKey: "host",
+		}},
+		WriteConnectionSecretToRef: &types.ObjectReference{Name: "my-app-connection"},
+	}
+
+	providerConfig, err := provider.GenerateProviderConfig(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateProviderConfig failed: %v", err)
+	}
+
+	if providerConfig.Kind != "ProviderConfig" || providerConfig.Name != "my-system-my-app-c364f582" || providerConfig.Namespace != "" {
+		t.Errorf("Unexpected ProviderConfig %s %s/%s", providerConfig.Kind, providerConfig.Namespace, providerConfig.Name)
+	}
+
+	expectedCredentials := CrossplaneProviderCredentials{
+		Source:    "Secret",
+		SecretRef: &CrossplaneSecretKeySelector{Name: "cluster-kubeconfig", Namespace: "crossplane-system", Key: "kubeconfig"},
+	}
+	if !reflect.DeepEqual(providerConfig.Spec.Credentials, expectedCredentials) {
+		t.Errorf("Expected credentials %+v, got %+v", expectedCredentials, providerConfig.Spec.Credentials)
+	}
+
+	release, err := provider.GenerateRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateRelease failed: %v", err)
+	}
+
+	if release.Spec.ProviderConfigRef.Name != "my-system-my-app-c364f582" {
+		t.Errorf("Expected the Release to use the generated ProviderConfig, got '%s'", release.Spec.ProviderConfigRef.Name)
+	}
+
+	if len(release.Spec.ConnectionDetails) != 1 || release.Spec.ConnectionDetails[0].Namespace != "my-system" {
+		t.Errorf("Expected a connection detail in the release namespace, got %+v", release.Spec.ConnectionDetails)
+	}
+
+	expectedSecret := &CrossplaneSecretReference{Name: "my-app-connection", Namespace: "my-system"}
+	if !reflect.DeepEqual(release.Spec.WriteConnectionSecretToRef, expectedSecret) {
+		t.Errorf("Expected connection secret %+v, got %+v", expectedSecret, release.Spec.WriteConnectionSecretToRef)
+	}
+
+	// An explicit reference names the generated ProviderConfig too
+	helmRelease.Spec.Crossplane.ProviderConfigRef = "workload-cluster"
+	helmRelease.Spec.Crossplane.ProviderConfig = &types.ProviderConfigSpec{}
+	providerConfig, err = provider.GenerateProviderConfig(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateProviderConfig failed: %v", err)
+	}
+	if providerConfig.Name != "workload-cluster" || providerConfig.Spec.Credentials.Source != "InjectedIdentity" {
+		t.Errorf("Expected an InjectedIdentity ProviderConfig 'workload-cluster', got '%s' with %+v", providerConfig.Name, providerConfig.Spec.Credentials)
+	}
+
+	// The Secret source needs a secret reference
+	helmRelease.Spec.Crossplane.ProviderConfig = &types.ProviderConfigSpec{Source: types.CredentialsSecret}
+	if _, err := provider.GenerateProviderConfig(helmRelease); err == nil {
+		t.Error("Expected an error for the Secret source without a secretRef")
+	}
+}
+
+// TestCrossplaneProvider_GenerateReleaseSet tests the mapping of set values to forProvider.set
+func TestCrossplaneProvider_GenerateReleaseSet(t *testing.T) {
+	// Load the crossplane example files
+	exampleDir := filepath.Join("..", "..", "examples", "crossplane")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Set = []types.SetValue{
+		{Name: "image.tag", Value: "1.2.3"},
+		{Name: "auth.password", ValueFrom: &types.ValueSource{SecretKeyRef: &types.KeySelector{Name: "db", Key: "password"}}},
+	}
+
+	provider := NewCrossplaneProvider()
+	release, err := provider.GenerateRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateRelease failed: %v", err)
+	}
+
+	expected := []CrossplaneSetVal{
+		{Name: "image.tag", Value: "1.2.3"},
+		{Name: "auth.password", ValueFrom: &CrossplaneValueFromSource{
+			SecretKeyRef: &CrossplaneDataKeySelector{Name: "db", Namespace: "my-system", Key: "password"},
+		}},
+	}
+	if !reflect.DeepEqual(release.Spec.ForProvider.Set, expected) {
+		t.Errorf("Expected set %+v, got %+v", expected, release.Spec.ForProvider.Set)
+	}
+}
+
+// TestCrossplaneProvider_GenerateReleasePatchesFrom tests the patches read from Secrets and ConfigMaps
+func TestCrossplaneProvider_GenerateReleasePatchesFrom(t *testing.T) {
+	// Load the crossplane example files
+	exampleDir := filepath.Join("..", "..", "examples", "crossplane")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Crossplane = &types.CrossplaneSpec{PatchesFrom: []types.ValuesReference{
+		{Kind: "ConfigMap", Name: "my-app-patches", Key: "patches.yaml"},
+		{Kind: "Secret", Name: "my-app-secret-patches", Key: "patches.yaml"},
+	}}
+
+	provider := NewCrossplaneProvider()
+	release, err := provider.GenerateRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateRelease failed: %v", err)
+	}
+
+	expected := []CrossplaneValueFromSource{
+		{ConfigMapKeyRef: &CrossplaneDataKeySelector{Name: "my-app-patches", Namespace: "my-system", Key: "patches.yaml"}},
+		{SecretKeyRef: &CrossplaneDataKeySelector{Name: "my-app-secret-patches", Namespace: "my-system", Key: "patches.yaml"}},
+	}
+	if !reflect.DeepEqual(release.Spec.ForProvider.PatchesFrom, expected) {
+		t.Errorf("Expected patchesFrom %+v, got %+v", expected, release.Spec.ForProvider.PatchesFrom)
+	}
+
+	// provider-helm has no default key for patches
+	helmRelease.Spec.Crossplane.PatchesFrom = []types.ValuesReference{{Kind: "ConfigMap", Name: "my-app-patches"}}
+	if _, err := provider.GenerateRelease(helmRelease); err == nil {
+		t.Error("Expected an error for a patch reference without a key")
+	}
+}
+
+// TestCrossplaneProvider_GenerateReleaseAuth tests the pull secret of the chart repository
+func TestCrossplaneProvider_GenerateReleaseAuth(t *testing.T) {
+	// Load the crossplane example files
+	exampleDir := filepath.Join("..", "..", "examples", "crossplane")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+	helmRelease.Spec.Chart.Auth = &types.ChartAuthSpec{SecretName: "repo-auth", InsecureSkipTLSVerify: true}
+
+	provider := NewCrossplaneProvider()
+	release, err := provider.GenerateRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateRelease failed: %v", err)
+	}
+
+	expected := &CrossplaneSecretReference{Name: "repo-auth", Namespace: "my-system"}
+	if !reflect.DeepEqual(release.Spec.ForProvider.Chart.PullSecretRef, expected) {
+		t.Errorf("Expected pullSecretRef %+v, got %+v", expected, release.Spec.ForProvider.Chart.PullSecretRef)
+	}
+
+	if !release.Spec.ForProvider.InsecureSkipTLSVerify {
+		t.Errorf("Expected insecureSkipTLSVerify to be set")
+	}
+}
@@ -0,0 +1,284 @@
+package fleet
+
+import (
+	"fmt"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// LocalWorkspace is the Fleet workspace holding only the cluster Fleet runs in
+	LocalWorkspace = "fleet-local"
+	// DefaultWorkspace is the Fleet workspace downstream clusters are registered in
+	DefaultWorkspace = "fleet-default"
+	// fleetYAMLKey is the key of the generated ConfigMap holding the fleet.yaml
+	fleetYAMLKey = "fleet.yaml"
+	// valuesKey is the key values files are read from unless another one is set
+	valuesKey = "values.yaml"
+)
+
+// FleetBundle represents a Rancher Fleet Bundle resource
+type FleetBundle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              FleetBundleSpec `json:"spec,omitempty"`
+}
+
+// FleetBundleSpec defines what a Bundle deploys and to which clusters
+type FleetBundleSpec struct {
+	FleetDeploymentOptions `json:",inline"`
+	Paused                 bool                `json:"paused,omitempty"`
+	Targets                []FleetBundleTarget `json:"targets"`
+}
+
+// FleetDeploymentOptions are the options of a Bundle which targets can override
+type FleetDeploymentOptions struct {
+	DefaultNamespace string            `json:"defaultNamespace,omitempty"`
+	Helm             *FleetHelmOptions `json:"helm,omitempty"`
+}
+
+// FleetHelmOptions defines the chart a Bundle installs
+type FleetHelmOptions struct {
+	ReleaseName    string                 `json:"releaseName,omitempty"`
+	Chart          string                 `json:"chart,omitempty"`
+	Repo           string                 `json:"repo,omitempty"`
+	Version        string                 `json:"version,omitempty"`
+	TimeoutSeconds int                    `json:"timeoutSeconds,omitempty"`
+	MaxHistory     int                    `json:"maxHistory,omitempty"`
+	Values         map[string]interface{} `json:"values,omitempty"`
+	ValuesFrom     []FleetValuesFrom      `json:"valuesFrom,omitempty"`
+}
+
+// FleetValuesFrom references the key of a Secret or ConfigMap holding a values file
+type FleetValuesFrom struct {
+	ConfigMapKeyRef *FleetKeySelector `json:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *FleetKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// FleetKeySelector selects a key of a Secret or ConfigMap in a namespace
+type FleetKeySelector struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key"`
+}
+
+// FleetBundleTarget selects clusters and customizes the deployment to them
+type FleetBundleTarget struct {
+	Name                   string                `json:"name"`
+	ClusterName            string                `json:"clusterName,omitempty"`
+	ClusterSelector        *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+	FleetDeploymentOptions `json:",inline"`
+}
+
+// FleetYAML is the fleet.yaml of a bundle in a git repository
+type FleetYAML struct {
+	FleetDeploymentOptions `json:",inline"`
+	Paused                 bool                `json:"paused,omitempty"`
+	TargetCustomizations   []FleetBundleTarget `json:"targetCustomizations,omitempty"`
+}
+
+// FleetConfigMap represents the ConfigMap holding a generated fleet.yaml
+type FleetConfigMap struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Data              map[string]string `json:"data"`
+}
+
+// FleetProvider handles the transformation of HelmRelease to Rancher Fleet resources
+type FleetProvider struct{}
+
+// NewFleetProvider creates a new Fleet provider instance
+func NewFleetProvider() *FleetProvider {
+	return &FleetProvider{}
+}
+
+// GenerateBundle creates a Fleet Bundle from a HelmRelease. Each destination becomes a target customizing
+// the values for that cluster and the cluster selector a target for the matching clusters. Without either
+// the Bundle targets every cluster of its workspace, which is only the local cluster by default.
+func (p *FleetProvider) GenerateBundle(helmRelease *types.HelmRelease) (*FleetBundle, error) {
+	options, err := deploymentOptions(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := destinationTargets(helmRelease)
+	if helmRelease.Spec.ClusterSelector != nil {
+		targets = append(targets, FleetBundleTarget{Name: "selected", ClusterSelector: helmRelease.Spec.ClusterSelector})
+	}
+
+	workspace := DefaultWorkspace
+	if len(targets) == 0 {
+		workspace = LocalWorkspace
+		targets = []FleetBundleTarget{{Name: "default", ClusterSelector: &metav1.LabelSelector{}}}
+	}
+	if helmRelease.Spec.Fleet != nil && helmRelease.Spec.Fleet.Workspace != "" {
+		workspace = helmRelease.Spec.Fleet.Workspace
+	}
+
+	bundle := &FleetBundle{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "fleet.cattle.io/v1alpha1",
+			Kind:       "Bundle",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      types.QualifiedName(helmRelease.ObjectMeta.Namespace, helmRelease.ObjectMeta.Name),
+			Namespace: workspace,
+		},
+		Spec: FleetBundleSpec{
+			FleetDeploymentOptions: *options,
+			Paused:                 paused(helmRelease),
+			Targets:                targets,
+		},
+	}
+
+	return bundle, nil
+}
+
+// UsesFleetYAML reports whether the HelmRelease asks for a fleet.yaml instead of a Bundle
+func (p *FleetProvider) UsesFleetYAML(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.Fleet != nil && helmRelease.Spec.Fleet.FleetYAML
+}
+
+// GenerateFleetYAML creates a ConfigMap holding the fleet.yaml of the release, for repositories deployed
+// by a Fleet GitRepo. The GitRepo selects the clusters, so destinations only become target customizations.
+func (p *FleetProvider) GenerateFleetYAML(helmRelease *types.HelmRelease) (*FleetConfigMap, error) {
+	options, err := deploymentOptions(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	fleetYAML := FleetYAML{
+		FleetDeploymentOptions: *options,
+		Paused:                 paused(helmRelease),
+		TargetCustomizations:   destinationTargets(helmRelease),
+	}
+
+	fleetYAMLBytes, err := yaml.Marshal(fleetYAML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fleet.yaml: %w", err)
+	}
+
+	configMap := &FleetConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helmRelease.ObjectMeta.Name + "-fleet",
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+		Data: map[string]string{fleetYAMLKey: string(fleetYAMLBytes)},
+	}
+
+	return configMap, nil
+}
+
+// deploymentOptions builds the helm options shared by the Bundle and the fleet.yaml. Fleet has no set
+// values, so the literal ones are merged into the values and the ones read from a Secret or ConfigMap
+// are skipped.
+func deploymentOptions(helmRelease *types.HelmRelease) (*FleetDeploymentOptions, error) {
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return nil, err
+	}
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	helm := &FleetHelmOptions{
+		ReleaseName: helmRelease.ObjectMeta.Name,
+		Chart:       helmRelease.Spec.Chart.Name,
+		Repo:        helmRelease.Spec.Chart.Repo,
+		Version:     helmRelease.Spec.Chart.Version,
+		Values:      chartValues,
+	}
+
+	if fleet := helmRelease.Spec.Fleet; fleet != nil {
+		for _, ref := range fleet.ValuesFrom {
+			valuesFrom, err := valuesFrom(ref, helmRelease)
+			if err != nil {
+				return nil, err
+			}
+			helm.ValuesFrom = append(helm.ValuesFrom, valuesFrom)
+		}
+	}
+
+	if err := applyLifecycleSpec(helm, helmRelease.Spec.Lifecycle); err != nil {
+		return nil, err
+	}
+
+	return &FleetDeploymentOptions{
+		DefaultNamespace: helmRelease.ObjectMeta.Namespace,
+		Helm:             helm,
+	}, nil
+}
+
+// destinationTargets returns a target per destination, matching the cluster registered under the
+// destination name and overriding the values and namespace for it
+func destinationTargets(helmRelease *types.HelmRelease) []FleetBundleTarget {
+	var targets []FleetBundleTarget
+	for _, destination := range helmRelease.Spec.Destinations {
+		target := FleetBundleTarget{
+			Name:        destination.Name,
+			ClusterName: destination.Name,
+		}
+		target.DefaultNamespace = destination.Namespace
+		if len(destination.Values) > 0 {
+			target.Helm = &FleetHelmOptions{Values: destination.Values}
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// valuesFrom maps a reference to a values Secret or ConfigMap in the namespace of the release
+func valuesFrom(ref types.ValuesReference, helmRelease *types.HelmRelease) (FleetValuesFrom, error) {
+	if ref.Name == "" {
+		return FleetValuesFrom{}, fmt.Errorf("values reference without a name")
+	}
+
+	selector := &FleetKeySelector{
+		Name:      ref.Name,
+		Namespace: helmRelease.ObjectMeta.Namespace,
+		Key:       ref.Key,
+	}
+	if selector.Key == "" {
+		selector.Key = valuesKey
+	}
+
+	switch ref.Kind {
+	case "Secret":
+		return FleetValuesFrom{SecretKeyRef: selector}, nil
+	case "ConfigMap":
+		return FleetValuesFrom{ConfigMapKeyRef: selector}, nil
+	default:
+		return FleetValuesFrom{}, fmt.Errorf("unsupported values reference kind: %s", ref.Kind)
+	}
+}
+
+// paused reports whether the release is suspended
+func paused(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.Lifecycle != nil && helmRelease.Spec.Lifecycle.Suspend
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have a Fleet equivalent
+func applyLifecycleSpec(helm *FleetHelmOptions, lifecycle *types.LifecycleSpec) error {
+	if lifecycle == nil {
+		return nil
+	}
+
+	timeout, err := lifecycle.TimeoutSeconds()
+	if err != nil {
+		return err
+	}
+	helm.TimeoutSeconds = timeout
+
+	if lifecycle.MaxHistory != nil {
+		helm.MaxHistory = *lifecycle.MaxHistory
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package fleet
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestNewFleetProvider(t *testing.T) {
+	provider := NewFleetProvider()
+	if provider == nil {
+		t.Error("NewFleetProvider returned nil")
+	}
+}
+
+// TestFleetProvider_GenerateBundleFromExample tests the Fleet provider using example files
+func TestFleetProvider_GenerateBundleFromExample(t *testing.T) {
+	// Load the fleet example files
+	exampleDir := filepath.Join("..", "..", "examples", "fleet")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Parse the HelmRelease from the example
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewFleetProvider()
+	bundle, err := provider.GenerateBundle(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateBundle failed: %v", err)
+	}
+
+	if bundle.APIVersion != "fleet.cattle.io/v1alpha1" || bundle.Kind != "Bundle" {
+		t.Errorf("Expected a fleet.cattle.io/v1alpha1 Bundle, got %s %s", bundle.APIVersion, bundle.Kind)
+	}
+
+	// Destinations are downstream clusters, registered in the default workspace
+	if bundle.Name != "my-system-my-app-c364f582" || bundle.Namespace != DefaultWorkspace {
+		t.Errorf("Expected %s/my-system-my-app-c364f582, got %s/%s", DefaultWorkspace, bundle.Namespace, bundle.Name)
+	}
+
+	expectedHelm := &FleetHelmOptions{
+		ReleaseName:    "my-app",
+		Chart:          "hello-world",
+		Repo:           "https://helm.github.io/examples",
+		Version:        "0.1.0",
+		TimeoutSeconds: 300,
+		Values:         map[string]interface{}{"replicaCount": float64(2)},
+	}
+	if !reflect.DeepEqual(bundle.Spec.Helm, expectedHelm) || bundle.Spec.DefaultNamespace != "my-system" {
+		t.Errorf("Expected helm options %+v in my-system, got %+v in %s", expectedHelm, bundle.Spec.Helm, bundle.Spec.DefaultNamespace)
+	}
+
+	expectedTargets := []FleetBundleTarget{
+		{Name: "staging", ClusterName: "staging"},
+		{Name: "production", ClusterName: "production", FleetDeploymentOptions: FleetDeploymentOptions{
+			Helm: &FleetHelmOptions{Values: map[string]interface{}{"replicaCount": float64(3)}},
+		}},
+	}
+	if !reflect.DeepEqual(bundle.Spec.Targets, expectedTargets) {
+		t.Errorf("Expected targets %+v, got %+v", expectedTargets, bundle.Spec.Targets)
+	}
+}
+
+// TestFleetProvider_GenerateBundleTargets tests the targets of a Bundle without destinations
+func TestFleetProvider_GenerateBundleTargets(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{Chart: types.ChartSpec{Name: "hello-world"}}}
+	helmRelease.Name = "my-app"
+
+	provider := NewFleetProvider()
+	bundle, err := provider.GenerateBundle(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateBundle failed: %v", err)
+	}
+
+	// Without destinations or selector the local cluster is targeted
+	expected := []FleetBundleTarget{{Name: "default", ClusterSelector: &metav1.LabelSelector{}}}
+	if bundle.Namespace != LocalWorkspace || !reflect.DeepEqual(bundle.Spec.Targets, expected) {
+		t.Errorf("Expected targets %+v in %s, got %+v in %s", expected, LocalWorkspace, bundle.Spec.Targets, bundle.Namespace)
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	helmRelease.Spec.ClusterSelector = selector
+	helmRelease.Spec.Fleet = &types.FleetSpec{Workspace: "fleet-prod"}
+
+	bundle, err = provider.GenerateBundle(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateBundle failed: %v", err)
+	}
+
+	expected = []FleetBundleTarget{{Name: "selected", ClusterSelector: selector}}
+	if bundle.Namespace != "fleet-prod" || !reflect.DeepEqual(bundle.Spec.Targets, expected) {
+		t.Errorf("Expected targets %+v in fleet-prod, got %+v in %s", expected, bundle.Spec.Targets, bundle.Namespace)
+	}
+}
+
+// TestFleetProvider_GenerateFleetYAML tests the fleet.yaml generated for a Fleet GitRepo
+func TestFleetProvider_GenerateFleetYAML(t *testing.T) {
+	maxHistory := 3
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart:        types.ChartSpec{Name: "hello-world", Repo: "https://helm.github.io/examples"},
+		Set:          []types.SetValue{{Name: "image.tag", Value: "1.2.3"}},
+		Destinations: []types.Destination{{Name: "production", Namespace: "prod"}},
+		Lifecycle:    &types.LifecycleSpec{Suspend: true, MaxHistory: &maxHistory},
+		Fleet: &types.FleetSpec{
+			FleetYAML:  true,
+			ValuesFrom: []types.ValuesReference{{Kind: "Secret", Name: "my-app-secrets", Key: "secrets.yaml"}},
+		},
+	}}
+	helmRelease.Name = "my-app"
+	helmRelease.Namespace = "my-system"
+
+	provider := NewFleetProvider()
+	if !provider.UsesFleetYAML(helmRelease) {
+		t.Fatal("Expected a fleet.yaml with spec.fleet.fleetYAML")
+	}
+
+	configMap, err := provider.GenerateFleetYAML(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateFleetYAML failed: %v", err)
+	}
+
+	if configMap.Kind != "ConfigMap" || configMap.Name != "my-app-fleet" || configMap.Namespace != "my-system" {
+		t.Errorf("Unexpected ConfigMap %s %s/%s", configMap.Kind, configMap.Namespace, configMap.Name)
+	}
+
+	var fleetYAML FleetYAML
+	if err := yaml.Unmarshal([]byte(configMap.Data["fleet.yaml"]), &fleetYAML); err != nil {
+		t.Fatalf("Failed to parse fleet.yaml: %v", err)
+	}
+
+	if !fleetYAML.Paused || fleetYAML.Helm.MaxHistory != 3 {
+		t.Errorf("Expected a paused fleet.yaml with maxHistory 3, got %+v", fleetYAML)
+	}
+
+	expectedValues := map[string]interface{}{"image": map[string]interface{}{"tag": "1.2.3"}}
+	if !reflect.DeepEqual(fleetYAML.Helm.Values, expectedValues) {
+		t.Errorf("Expected values %v, got %v", expectedValues, fleetYAML.Helm.Values)
+	}
+
+	expectedValuesFrom := []FleetValuesFrom{{SecretKeyRef: &FleetKeySelector{Name: "my-app-secrets", Namespace: "my-system", Key: "secrets.yaml"}}}
+	if !reflect.DeepEqual(fleetYAML.Helm.ValuesFrom, expectedValuesFrom) {
+		t.Errorf("Expected valuesFrom %+v, got %+v", expectedValuesFrom, fleetYAML.Helm.ValuesFrom)
+	}
+
+	expectedCustomizations := []FleetBundleTarget{{Name: "production", ClusterName: "production", FleetDeploymentOptions: FleetDeploymentOptions{DefaultNamespace: "prod"}}}
+	if !reflect.DeepEqual(fleetYAML.TargetCustomizations, expectedCustomizations) {
+		t.Errorf("Expected target customizations %+v, got %+v", expectedCustomizations, fleetYAML.TargetCustomizations)
+	}
+}
@@ -1,10 +1,21 @@
 package fluxcd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// DefaultReleaseInterval is how often Flux reconciles the HelmRelease
+	DefaultReleaseInterval = "5m"
+	// DefaultRepositoryInterval is how often Flux refreshes the HelmRepository index
+	DefaultRepositoryInterval = "1m"
+)
+
 // FluxCDHelmRelease represents a FluxCD HelmRelease resource
 type FluxCDHelmRelease struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -14,8 +25,113 @@ type FluxCDHelmRelease struct {
 
 // FluxCDHelmReleaseSpec defines the desired state of FluxCD HelmRelease
 type FluxCDHelmReleaseSpec struct {
-	// For phase 1, we'll keep this minimal
-	// Full spec will be added in future phases
+	Interval       string                      `json:"interval"`
+	Chart          FluxCDHelmChartTemplate     `json:"chart"`
+	DependsOn      []FluxCDDependencyReference `json:"dependsOn,omitempty"`
+	Timeout        string                      `json:"timeout,omitempty"`
+	MaxHistory     *int                        `json:"maxHistory,omitempty"`
+	Suspend        bool                        `json:"suspend,omitempty"`
+	Install        *FluxCDInstall              `json:"install,omitempty"`
+	Upgrade        *FluxCDUpgrade              `json:"upgrade,omitempty"`
+	Test           *FluxCDTest                 `json:"test,omitempty"`
+	Rollback       *FluxCDRollback             `json:"rollback,omitempty"`
+	DriftDetection *FluxCDDriftDetection       `json:"driftDetection,omitempty"`
+	ValuesFrom     []FluxCDValuesReference     `json:"valuesFrom,omitempty"`
+	Values         map[string]interface{}      `json:"values,omitempty"`
+}
+
+// FluxCDValuesReference reads values from a key of a Secret or ConfigMap, either a whole values document or
+// a single value placed at the target path
+type FluxCDValuesReference struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	ValuesKey  string `json:"valuesKey,omitempty"`
+	TargetPath string `json:"targetPath,omitempty"`
+	Optional   bool   `json:"optional,omitempty"`
+}
+
+// FluxCDDependencyReference references a HelmRelease which must be ready first
+type FluxCDDependencyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// FluxCDHelmChartTemplate defines the HelmChart Flux creates for the HelmRelease
+type FluxCDHelmChartTemplate struct {
+	Spec FluxCDHelmChartTemplateSpec `json:"spec"`
+}
+
+// FluxCDHelmChartTemplateSpec defines the chart and the source it is fetched from
+type FluxCDHelmChartTemplateSpec struct {
+	Chart             string                              `json:"chart"`
+	Version           string                              `json:"version,omitempty"`
+	SourceRef         FluxCDCrossNamespaceObjectReference `json:"sourceRef"`
+	Interval          string                              `json:"interval,omitempty"`
+	ReconcileStrategy string                              `json:"reconcileStrategy,omitempty"`
+	ValuesFiles       []string                            `json:"valuesFiles,omitempty"`
+	Verify            *FluxCDOCIVerification              `json:"verify,omitempty"`
+}
+
+// FluxCDOCIVerification defines how the signature of an OCI chart is verified
+type FluxCDOCIVerification struct {
+	Provider  string                      `json:"provider"`
+	SecretRef *types.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// FluxCDCrossNamespaceObjectReference references a Flux source, possibly in another namespace
+type FluxCDCrossNamespaceObjectReference struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// FluxCDInstall defines the Helm install action
+type FluxCDInstall struct {
+	CreateNamespace bool               `json:"createNamespace,omitempty"`
+	Remediation     *FluxCDRemediation `json:"remediation,omitempty"`
+}
+
+// FluxCDUpgrade defines the Helm upgrade action
+type FluxCDUpgrade struct {
+	Remediation *FluxCDRemediation `json:"remediation,omitempty"`
+}
+
+// FluxCDRemediation defines what happens when a Helm action fails
+type FluxCDRemediation struct {
+	Retries              int    `json:"retries,omitempty"`
+	RemediateLastFailure *bool  `json:"remediateLastFailure,omitempty"`
+	Strategy             string `json:"strategy,omitempty"`
+}
+
+// FluxCDTest defines the Helm test action
+type FluxCDTest struct {
+	Enable         bool `json:"enable,omitempty"`
+	IgnoreFailures bool `json:"ignoreFailures,omitempty"`
+}
+
+// FluxCDRollback defines the Helm rollback action
+type FluxCDRollback struct {
+	CleanupOnFail bool `json:"cleanupOnFail,omitempty"`
+	Force         bool `json:"force,omitempty"`
+	Recreate      bool `json:"recreate,omitempty"`
+}
+
+// FluxCDDriftDetection defines how changes made to the release resources in the cluster are handled
+type FluxCDDriftDetection struct {
+	Mode   string             `json:"mode,omitempty"`
+	Ignore []FluxCDIgnoreRule `json:"ignore,omitempty"`
+}
+
+// FluxCDIgnoreRule excludes fields of matching resources from drift detection
+type FluxCDIgnoreRule struct {
+	Paths  []string        `json:"paths"`
+	Target *FluxCDSelector `json:"target,omitempty"`
+}
+
+// FluxCDSelector selects resources by group and kind
+type FluxCDSelector struct {
+	Group string `json:"group,omitempty"`
+	Kind  string `json:"kind,omitempty"`
 }
 
 // FluxCDHelmRepository represents a FluxCD HelmRepository resource
@@ -27,8 +143,12 @@ type FluxCDHelmRepository struct {
 
 // FluxCDHelmRepositorySpec defines the desired state of FluxCD HelmRepository
 type FluxCDHelmRepositorySpec struct {
-	// For phase 1, we'll keep this minimal
-	// Full spec will be added in future phases
+	Interval        string                      `json:"interval"`
+	URL             string                      `json:"url"`
+	Type            string                      `json:"type,omitempty"`
+	SecretRef       *types.LocalObjectReference `json:"secretRef,omitempty"`
+	CertSecretRef   *types.LocalObjectReference `json:"certSecretRef,omitempty"`
+	PassCredentials bool                        `json:"passCredentials,omitempty"`
 }
 
 // FluxCDProvider handles the transformation of HelmRelease to FluxCD resources
@@ -41,10 +161,15 @@ func NewFluxCDProvider() *FluxCDProvider {
 
 // GenerateHelmRelease creates a FluxCD HelmRelease resource from a HelmRelease
 func (p *FluxCDProvider) GenerateHelmRelease(helmRelease *types.HelmRelease) (*FluxCDHelmRelease, error) {
+	releaseValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the FluxCD HelmRelease with basic structure
 	fluxHelmRelease := &FluxCDHelmRelease{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "helm.toolkit.fluxcd.io/v2beta1",
+			APIVersion: "helm.toolkit.fluxcd.io/v2",
 			Kind:       "HelmRelease",
 		},
 		ObjectMeta: metav1.ObjectMeta{
@@ -52,29 +177,258 @@ func (p *FluxCDProvider) GenerateHelmRelease(helmRelease *types.HelmRelease) (*F
 			Namespace: helmRelease.ObjectMeta.Namespace,
 		},
 		Spec: FluxCDHelmReleaseSpec{
-			// Minimal spec for phase 1
+			Interval: DefaultReleaseInterval,
+			Chart: FluxCDHelmChartTemplate{
+				Spec: FluxCDHelmChartTemplateSpec{
+					Chart:     helmRelease.Spec.Chart.Name,
+					Version:   helmRelease.Spec.Chart.Version,
+					SourceRef: sourceRef(helmRelease),
+				},
+			},
+			ValuesFrom: valuesFrom(helmRelease.Spec.Set),
+			Values:     releaseValues,
 		},
 	}
 
+	if err := applyChartSpec(&fluxHelmRelease.Spec.Chart.Spec, helmRelease.Spec.Chart); err != nil {
+		return nil, err
+	}
+
+	applySyncSpec(&fluxHelmRelease.Spec, helmRelease.Spec.Sync)
+
+	if err := applyLifecycleSpec(&fluxHelmRelease.Spec, helmRelease.Spec.Lifecycle); err != nil {
+		return nil, err
+	}
+
+	// References to the same namespace are written without one, as Flux defaults to it
+	for _, dependency := range helmRelease.Spec.DependsOn {
+		if dependency.Name == "" {
+			return nil, fmt.Errorf("dependency without a name")
+		}
+		reference := FluxCDDependencyReference{Name: dependency.Name}
+		if dependency.Namespace != "" && dependency.Namespace != helmRelease.ObjectMeta.Namespace {
+			reference.Namespace = dependency.Namespace
+		}
+		fluxHelmRelease.Spec.DependsOn = append(fluxHelmRelease.Spec.DependsOn, reference)
+	}
+
 	return fluxHelmRelease, nil
 }
 
+// valuesFrom returns the references of the set values read from a Secret or ConfigMap, each placed at the
+// path of the value
+func valuesFrom(sets []types.SetValue) []FluxCDValuesReference {
+	var references []FluxCDValuesReference
+	for _, set := range sets {
+		if set.ValueFrom == nil {
+			continue
+		}
+
+		kind := "Secret"
+		if set.ValueFrom.ConfigMapKeyRef != nil {
+			kind = "ConfigMap"
+		}
+		ref := values.KeyRef(set.ValueFrom)
+		references = append(references, FluxCDValuesReference{
+			Kind:       kind,
+			Name:       ref.Name,
+			ValuesKey:  ref.Key,
+			TargetPath: set.Name,
+			Optional:   ref.Optional,
+		})
+	}
+	return references
+}
+
+// applyChartSpec maps the chart source settings onto the HelmChart template. Flux only verifies the
+// signatures of charts pulled from OCI registries.
+func applyChartSpec(spec *FluxCDHelmChartTemplateSpec, chart types.ChartSpec) error {
+	switch chart.ReconcileStrategy {
+	case "", types.ReconcileStrategyChartVersion, types.ReconcileStrategyRevision:
+	default:
+		return fmt.Errorf("unsupported chart reconcile strategy: %s", chart.ReconcileStrategy)
+	}
+
+	spec.Interval = chart.Interval
+	spec.ReconcileStrategy = chart.ReconcileStrategy
+	spec.ValuesFiles = chart.ValuesFiles
+
+	if chart.Verify != nil {
+		if !strings.HasPrefix(chart.Repo, "oci://") {
+			return fmt.Errorf("chart verification is only supported for oci:// repositories")
+		}
+
+		provider := chart.Verify.Provider
+		switch provider {
+		case "":
+			provider = "cosign"
+		case "cosign", "notation":
+		default:
+			return fmt.Errorf("unsupported chart verification provider: %s", provider)
+		}
+
+		spec.Verify = &FluxCDOCIVerification{Provider: provider, SecretRef: types.LocalReference(chart.Verify.SecretName)}
+	}
+
+	return nil
+}
+
+// applySyncSpec maps the portable sync settings onto the closest Flux equivalents. Flux always reconciles
+// automatically and Helm prunes removed resources itself, so automated and prune need no mapping, and
+// server side apply is not configurable.
+func applySyncSpec(spec *FluxCDHelmReleaseSpec, sync *types.SyncSpec) {
+	if sync == nil {
+		return
+	}
+
+	if sync.CreateNamespace {
+		spec.Install = &FluxCDInstall{CreateNamespace: true}
+	}
+
+	if sync.Retry != nil && sync.Retry.Limit > 0 {
+		if spec.Install == nil {
+			spec.Install = &FluxCDInstall{}
+		}
+		spec.Install.Remediation = &FluxCDRemediation{Retries: sync.Retry.Limit}
+		spec.Upgrade = &FluxCDUpgrade{Remediation: &FluxCDRemediation{Retries: sync.Retry.Limit}}
+	}
+
+	// Ignore rules only matter when drift detection is on, which is what self heal asks for
+	if sync.SelfHeal {
+		spec.DriftDetection = &FluxCDDriftDetection{Mode: "enabled"}
+		if sync.IgnoreCRDDrift {
+			spec.DriftDetection.Ignore = []FluxCDIgnoreRule{{
+				Paths:  []string{"/spec"},
+				Target: &FluxCDSelector{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"},
+			}}
+		}
+	}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings onto the HelmRelease. They are applied after the
+// sync settings and take precedence over them.
+func applyLifecycleSpec(spec *FluxCDHelmReleaseSpec, lifecycle *types.LifecycleSpec) error {
+	if lifecycle == nil {
+		return nil
+	}
+
+	if lifecycle.Install != nil {
+		if lifecycle.Install.Strategy != "" {
+			return fmt.Errorf("remediation strategy is only supported for upgrades")
+		}
+		if spec.Install == nil {
+			spec.Install = &FluxCDInstall{}
+		}
+		spec.Install.Remediation = remediation(lifecycle.Install)
+	}
+
+	if lifecycle.Upgrade != nil {
+		switch lifecycle.Upgrade.Strategy {
+		case "", types.RemediationRollback, types.RemediationUninstall:
+		default:
+			return fmt.Errorf("unsupported remediation strategy: %s", lifecycle.Upgrade.Strategy)
+		}
+		spec.Upgrade = &FluxCDUpgrade{Remediation: remediation(lifecycle.Upgrade)}
+	}
+
+	if lifecycle.Rollback != nil {
+		spec.Rollback = &FluxCDRollback{
+			CleanupOnFail: lifecycle.Rollback.CleanupOnFail,
+			Force:         lifecycle.Rollback.Force,
+			Recreate:      lifecycle.Rollback.Recreate,
+		}
+	}
+
+	if lifecycle.Test != nil {
+		spec.Test = &FluxCDTest{Enable: lifecycle.Test.Enable, IgnoreFailures: lifecycle.Test.IgnoreFailures}
+	}
+
+	if lifecycle.DriftDetection != nil {
+		switch lifecycle.DriftDetection.Mode {
+		case types.DriftDetectionEnabled, types.DriftDetectionWarn, types.DriftDetectionDisabled:
+		default:
+			return fmt.Errorf("unsupported drift detection mode: %s", lifecycle.DriftDetection.Mode)
+		}
+		// Keep the ignore rules derived from the sync settings
+		if spec.DriftDetection == nil {
+			spec.DriftDetection = &FluxCDDriftDetection{}
+		}
+		spec.DriftDetection.Mode = lifecycle.DriftDetection.Mode
+	}
+
+	spec.Timeout = lifecycle.Timeout
+	spec.Suspend = lifecycle.Suspend
+	spec.MaxHistory = lifecycle.MaxHistory
+
+	return nil
+}
+
+// remediation converts the portable remediation settings
+func remediation(spec *types.RemediationSpec) *FluxCDRemediation {
+	return &FluxCDRemediation{
+		Retries:              spec.Retries,
+		RemediateLastFailure: spec.RemediateLastFailure,
+		Strategy:             spec.Strategy,
+	}
+}
+
+// UsesHelmRepository reports whether a HelmRepository is generated for the HelmRelease, which is not the
+// case when it references an existing one
+func (p *FluxCDProvider) UsesHelmRepository(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.FluxCD == nil || helmRelease.Spec.FluxCD.SourceRef == nil
+}
+
+// sourceRef returns the reference to the HelmRepository the chart is fetched from
+func sourceRef(helmRelease *types.HelmRelease) FluxCDCrossNamespaceObjectReference {
+	reference := FluxCDCrossNamespaceObjectReference{
+		Kind:      "HelmRepository",
+		Name:      types.RepositoryName(helmRelease.Spec.Chart.Repo),
+		Namespace: helmRelease.ObjectMeta.Namespace,
+	}
+
+	if helmRelease.Spec.FluxCD != nil && helmRelease.Spec.FluxCD.SourceRef != nil {
+		reference.Name = helmRelease.Spec.FluxCD.SourceRef.Name
+		if namespace := helmRelease.Spec.FluxCD.SourceRef.Namespace; namespace != "" {
+			reference.Namespace = namespace
+		}
+	}
+
+	return reference
+}
+
 // GenerateHelmRepository creates a FluxCD HelmRepository resource from a HelmRelease
 func (p *FluxCDProvider) GenerateHelmRepository(helmRelease *types.HelmRelease) (*FluxCDHelmRepository, error) {
+	if helmRelease.Spec.Chart.Repo == "" {
+		return nil, fmt.Errorf("spec.chart.repo is required to generate a HelmRepository")
+	}
+
 	// Create the FluxCD HelmRepository with basic structure
 	helmRepo := &FluxCDHelmRepository{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "source.toolkit.fluxcd.io/v1beta1",
+			APIVersion: "source.toolkit.fluxcd.io/v1",
 			Kind:       "HelmRepository",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      helmRelease.ObjectMeta.Name,
+			Name:      types.RepositoryName(helmRelease.Spec.Chart.Repo),
 			Namespace: helmRelease.ObjectMeta.Namespace,
 		},
 		Spec: FluxCDHelmRepositorySpec{
-			// Minimal spec for phase 1
+			Interval: DefaultRepositoryInterval,
+			URL:      helmRelease.Spec.Chart.Repo,
 		},
 	}
 
+	// OCI registries are a different kind of HelmRepository
+	if strings.HasPrefix(helmRelease.Spec.Chart.Repo, "oci://") {
+		helmRepo.Spec.Type = "oci"
+	}
+
+	// The referenced Secrets are read from the namespace of the HelmRepository
+	if auth := helmRelease.Spec.Chart.Auth; auth != nil {
+		helmRepo.Spec.SecretRef = types.LocalReference(auth.SecretName)
+		helmRepo.Spec.CertSecretRef = types.LocalReference(auth.CertSecretName)
+		helmRepo.Spec.PassCredentials = auth.PassCredentials
+	}
+
 	return helmRepo, nil
 }
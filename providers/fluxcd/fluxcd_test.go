@@ -2,8 +2,10 @@ package fluxcd
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
 	"github.com/kubed-io/krm-helm-fn/testutil"
 )
 
@@ -37,8 +39,8 @@ func TestFluxCDProvider_GenerateHelmReleaseFromExample(t *testing.T) {
 	}
 
 	// Verify the generated helm release has correct apiVersion and kind
-	if fluxHelmRelease.APIVersion != "helm.toolkit.fluxcd.io/v2beta1" {
-		t.Errorf("Expected APIVersion 'helm.toolkit.fluxcd.io/v2beta1', got '%s'", fluxHelmRelease.APIVersion)
+	if fluxHelmRelease.APIVersion != "helm.toolkit.fluxcd.io/v2" {
+		t.Errorf("Expected APIVersion 'helm.toolkit.fluxcd.io/v2', got '%s'", fluxHelmRelease.APIVersion)
 	}
 
 	if fluxHelmRelease.Kind != "HelmRelease" {
@@ -81,8 +83,8 @@ func TestFluxCDProvider_GenerateHelmRepositoryFromExample(t *testing.T) {
 	}
 
 	// Verify the generated helm repository has correct apiVersion and kind
-	if helmRepo.APIVersion != "source.toolkit.fluxcd.io/v1beta1" {
-		t.Errorf("Expected APIVersion 'source.toolkit.fluxcd.io/v1beta1', got '%s'", helmRepo.APIVersion)
+	if helmRepo.APIVersion != "source.toolkit.fluxcd.io/v1" {
+		t.Errorf("Expected APIVersion 'source.toolkit.fluxcd.io/v1', got '%s'", helmRepo.APIVersion)
 	}
 
 	if helmRepo.Kind != "HelmRepository" {
@@ -90,8 +92,8 @@ func TestFluxCDProvider_GenerateHelmRepositoryFromExample(t *testing.T) {
 	}
 
 	// Verify metadata is set correctly
-	if helmRepo.ObjectMeta.Name != "my-app" {
-		t.Errorf("Expected Name 'my-app', got '%s'", helmRepo.ObjectMeta.Name)
+	if helmRepo.ObjectMeta.Name != "helm-github-io-examples" {
+		t.Errorf("Expected Name 'helm-github-io-examples', got '%s'", helmRepo.ObjectMeta.Name)
 	}
 
 	if helmRepo.ObjectMeta.Namespace != "my-system" {
@@ -101,3 +103,266 @@ func TestFluxCDProvider_GenerateHelmRepositoryFromExample(t *testing.T) {
 	// For fluxcd provider in hello world state, we just check basic structure
 	// Future tests will validate the full spec content
 }
+
+// TestFluxCDProvider_GenerateHelmReleaseSpec tests the chart template and the mapping of the portable sync settings
+func TestFluxCDProvider_GenerateHelmReleaseSpec(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Sync = &types.SyncSpec{
+		Automated:       true,
+		SelfHeal:        true,
+		Retry:           &types.RetrySpec{Limit: 3},
+		CreateNamespace: true,
+		IgnoreCRDDrift:  true,
+	}
+
+	provider := NewFluxCDProvider()
+	fluxHelmRelease, err := provider.GenerateHelmRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmRelease failed: %v", err)
+	}
+
+	spec := fluxHelmRelease.Spec
+	if spec.Interval != "5m" {
+		t.Errorf("Expected interval '5m', got '%s'", spec.Interval)
+	}
+
+	chart := spec.Chart.Spec
+	if chart.Chart != "hello-world" || chart.Version != "0.1.0" || chart.SourceRef.Kind != "HelmRepository" || chart.SourceRef.Name != "helm-github-io-examples" {
+		t.Errorf("Unexpected chart template %+v", chart)
+	}
+
+	if spec.Values["replicaCount"] != float64(2) {
+		t.Errorf("Expected inline values, got %v", spec.Values)
+	}
+
+	if spec.Install == nil || !spec.Install.CreateNamespace || spec.Install.Remediation == nil || spec.Install.Remediation.Retries != 3 {
+		t.Errorf("Unexpected install settings %+v", spec.Install)
+	}
+
+	if spec.Upgrade == nil || spec.Upgrade.Remediation == nil || spec.Upgrade.Remediation.Retries != 3 {
+		t.Errorf("Unexpected upgrade settings %+v", spec.Upgrade)
+	}
+
+	if spec.DriftDetection == nil || spec.DriftDetection.Mode != "enabled" || len(spec.DriftDetection.Ignore) != 1 {
+		t.Errorf("Expected drift detection ignoring CRDs, got %+v", spec.DriftDetection)
+	}
+
+	helmRepo, err := provider.GenerateHelmRepository(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmRepository failed: %v", err)
+	}
+
+	if helmRepo.Spec.URL != "https://helm.github.io/examples" || helmRepo.Spec.Interval != "1m" || helmRepo.Spec.Type != "" {
+		t.Errorf("Unexpected repository spec %+v", helmRepo.Spec)
+	}
+}
+
+// TestFluxCDProvider_GenerateHelmReleaseLifecycle tests the mapping of the portable lifecycle settings
+func TestFluxCDProvider_GenerateHelmReleaseLifecycle(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	maxHistory := 3
+	helmRelease.Spec.Sync = &types.SyncSpec{SelfHeal: true, IgnoreCRDDrift: true, Retry: &types.RetrySpec{Limit: 2}}
+	helmRelease.Spec.DependsOn = []types.ReleaseReference{{Name: "cert-manager", Namespace: "cert-manager"}, {Name: "ingress", Namespace: "my-system"}}
+	helmRelease.Spec.Lifecycle = &types.LifecycleSpec{
+		Upgrade:        &types.RemediationSpec{Retries: 5, Strategy: types.RemediationRollback},
+		Rollback:       &types.RollbackSpec{CleanupOnFail: true},
+		Test:           &types.TestSpec{Enable: true},
+		DriftDetection: &types.DriftDetectionSpec{Mode: types.DriftDetectionWarn},
+		Timeout:        "10m",
+		Suspend:        true,
+		MaxHistory:     &maxHistory,
+	}
+
+	provider := NewFluxCDProvider()
+	fluxHelmRelease, err := provider.GenerateHelmRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmRelease failed: %v", err)
+	}
+
+	spec := fluxHelmRelease.Spec
+	expectedDependsOn := []FluxCDDependencyReference{{Name: "cert-manager", Namespace: "cert-manager"}, {Name: "ingress"}}
+	if !reflect.DeepEqual(spec.DependsOn, expectedDependsOn) {
+		t.Errorf("Expected dependsOn %+v, got %+v", expectedDependsOn, spec.DependsOn)
+	}
+
+	// The lifecycle settings win over the sync settings, the rest of the sync settings is kept
+	if spec.Upgrade.Remediation.Retries != 5 || spec.Upgrade.Remediation.Strategy != "rollback" {
+		t.Errorf("Unexpected upgrade remediation %+v", spec.Upgrade.Remediation)
+	}
+	if spec.Install.Remediation.Retries != 2 {
+		t.Errorf("Expected install retries from the sync settings, got %+v", spec.Install.Remediation)
+	}
+	if spec.DriftDetection.Mode != "warn" || len(spec.DriftDetection.Ignore) != 1 {
+		t.Errorf("Unexpected drift detection %+v", spec.DriftDetection)
+	}
+
+	if spec.Rollback == nil || !spec.Rollback.CleanupOnFail || spec.Test == nil || !spec.Test.Enable {
+		t.Errorf("Unexpected rollback %+v or test %+v", spec.Rollback, spec.Test)
+	}
+
+	if spec.Timeout != "10m" || !spec.Suspend || spec.MaxHistory == nil || *spec.MaxHistory != 3 {
+		t.Errorf("Unexpected timeout '%s', suspend %v or max history %v", spec.Timeout, spec.Suspend, spec.MaxHistory)
+	}
+
+	// Invalid settings are rejected
+	helmRelease.Spec.Lifecycle = &types.LifecycleSpec{DriftDetection: &types.DriftDetectionSpec{Mode: "always"}}
+	if _, err := provider.GenerateHelmRelease(helmRelease); err == nil {
+		t.Error("Expected an error for an unsupported drift detection mode")
+	}
+
+	helmRelease.Spec.Lifecycle = &types.LifecycleSpec{Install: &types.RemediationSpec{Strategy: types.RemediationRollback}}
+	if _, err := provider.GenerateHelmRelease(helmRelease); err == nil {
+		t.Error("Expected an error for an install remediation strategy")
+	}
+}
+
+// TestFluxCDProvider_GenerateHelmReleaseChartSettings tests the chart source settings and their validation
+func TestFluxCDProvider_GenerateHelmReleaseChartSettings(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Chart.Repo = "oci://ghcr.io/stefanprodan/charts"
+	helmRelease.Spec.Chart.Interval = "10m"
+	helmRelease.Spec.Chart.ReconcileStrategy = types.ReconcileStrategyRevision
+	helmRelease.Spec.Chart.ValuesFiles = []string{"values.yaml", "values-prod.yaml"}
+	helmRelease.Spec.Chart.Verify = &types.VerifySpec{SecretName: "cosign-pub"}
+
+	provider := NewFluxCDProvider()
+	fluxHelmRelease, err := provider.GenerateHelmRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmRelease failed: %v", err)
+	}
+
+	chart := fluxHelmRelease.Spec.Chart.Spec
+	if chart.Interval != "10m" || chart.ReconcileStrategy != "Revision" {
+		t.Errorf("Unexpected interval '%s' or reconcile strategy '%s'", chart.Interval, chart.ReconcileStrategy)
+	}
+
+	if !reflect.DeepEqual(chart.ValuesFiles, []string{"values.yaml", "values-prod.yaml"}) {
+		t.Errorf("Unexpected values files %v", chart.ValuesFiles)
+	}
+
+	expectedVerify := &FluxCDOCIVerification{Provider: "cosign", SecretRef: &types.LocalObjectReference{Name: "cosign-pub"}}
+	if !reflect.DeepEqual(chart.Verify, expectedVerify) {
+		t.Errorf("Expected verify %+v, got %+v", expectedVerify, chart.Verify)
+	}
+
+	// Verification is only supported for OCI repositories
+	helmRelease.Spec.Chart.Repo = "https://helm.github.io/examples"
+	if _, err := provider.GenerateHelmRelease(helmRelease); err == nil {
+		t.Error("Expected an error when verifying a chart from an HTTP repository")
+	}
+
+	helmRelease.Spec.Chart.Verify = nil
+	helmRelease.Spec.Chart.ReconcileStrategy = "Always"
+	if _, err := provider.GenerateHelmRelease(helmRelease); err == nil {
+		t.Error("Expected an error for an unsupported reconcile strategy")
+	}
+}
+
+// TestFluxCDProvider_GenerateHelmReleaseSet tests that set values end up in the values or valuesFrom
+func TestFluxCDProvider_GenerateHelmReleaseSet(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Set = []types.SetValue{
+		{Name: "replicaCount", Value: "3"},
+		{Name: "auth.password", ValueFrom: &types.ValueSource{SecretKeyRef: &types.KeySelector{Name: "db", Key: "password"}}},
+		{Name: "region", ValueFrom: &types.ValueSource{ConfigMapKeyRef: &types.KeySelector{Name: "cluster", Key: "region", Optional: true}}},
+	}
+
+	provider := NewFluxCDProvider()
+	fluxHelmRelease, err := provider.GenerateHelmRelease(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmRelease failed: %v", err)
+	}
+
+	if fluxHelmRelease.Spec.Values["replicaCount"] != int64(3) {
+		t.Errorf("Expected replicaCount 3, got %v", fluxHelmRelease.Spec.Values["replicaCount"])
+	}
+
+	expectedValuesFrom := []FluxCDValuesReference{
+		{Kind: "Secret", Name: "db", ValuesKey: "password", TargetPath: "auth.password"},
+		{Kind: "ConfigMap", Name: "cluster", ValuesKey: "region", TargetPath: "region", Optional: true},
+	}
+	if !reflect.DeepEqual(fluxHelmRelease.Spec.ValuesFrom, expectedValuesFrom) {
+		t.Errorf("Expected valuesFrom %+v, got %+v", expectedValuesFrom, fluxHelmRelease.Spec.ValuesFrom)
+	}
+
+	// The release values are left untouched
+	if helmRelease.Spec.Values["replicaCount"] != float64(2) {
+		t.Errorf("Expected the HelmRelease values not to be modified, got %v", helmRelease.Spec.Values)
+	}
+}
+
+// TestFluxCDProvider_GenerateHelmRepositoryAuth tests the credentials of the generated HelmRepository
+func TestFluxCDProvider_GenerateHelmRepositoryAuth(t *testing.T) {
+	// Load the fluxcd example files
+	exampleDir := filepath.Join("..", "..", "examples", "fluxcd")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+	helmRelease.Spec.Chart.Auth = &types.ChartAuthSpec{SecretName: "repo-auth", CertSecretName: "repo-tls", PassCredentials: true}
+
+	provider := NewFluxCDProvider()
+	helmRepo, err := provider.GenerateHelmRepository(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmRepository failed: %v", err)
+	}
+
+	spec := helmRepo.Spec
+	if !reflect.DeepEqual(spec.SecretRef, &types.LocalObjectReference{Name: "repo-auth"}) ||
+		!reflect.DeepEqual(spec.CertSecretRef, &types.LocalObjectReference{Name: "repo-tls"}) {
+		t.Errorf("Unexpected references %+v, %+v", spec.SecretRef, spec.CertSecretRef)
+	}
+
+	if !spec.PassCredentials {
+		t.Errorf("Expected passCredentials to be set")
+	}
+}
@@ -0,0 +1,289 @@
+package helmfile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// DefaultName is the name of the ConfigMap holding the helmfile.yaml unless told otherwise
+	DefaultName = "helmfile"
+	// DefaultPath is the file the helmfile.yaml is written to in the file mode unless told otherwise
+	DefaultPath = "helmfile.yaml"
+	// HelmfileKey is the key of the ConfigMap holding the helmfile.yaml
+	HelmfileKey = "helmfile.yaml"
+)
+
+// Helmfile is a helmfile.yaml
+type Helmfile struct {
+	Repositories []HelmfileRepository `json:"repositories,omitempty"`
+	Releases     []HelmfileRelease    `json:"releases"`
+}
+
+// HelmfileRepository is a chart repository charts are referenced from by its name
+type HelmfileRepository struct {
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	OCI             bool   `json:"oci,omitempty"`
+	PassCredentials bool   `json:"passCredentials,omitempty"`
+	SkipTLSVerify   bool   `json:"skipTLSVerify,omitempty"`
+}
+
+// HelmfileRelease is a release installed by helmfile
+type HelmfileRelease struct {
+	Name            string                   `json:"name"`
+	Namespace       string                   `json:"namespace"`
+	Chart           string                   `json:"chart"`
+	Version         string                   `json:"version,omitempty"`
+	CreateNamespace bool                     `json:"createNamespace"`
+	Timeout         int                      `json:"timeout,omitempty"`
+	Values          []map[string]interface{} `json:"values,omitempty"`
+	Needs           []string                 `json:"needs,omitempty"`
+}
+
+// HelmfileConfigMap represents the ConfigMap holding a generated helmfile.yaml
+type HelmfileConfigMap struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Data              map[string]string `json:"data"`
+}
+
+// HelmfileProvider handles the transformation of HelmRelease to helmfile.yaml
+type HelmfileProvider struct{}
+
+// NewHelmfileProvider creates a new helmfile provider instance
+func NewHelmfileProvider() *HelmfileProvider {
+	return &HelmfileProvider{}
+}
+
+// GenerateHelmfile creates a helmfile.yaml installing the chart of the HelmRelease. Releases are only
+// ordered by their needs, so the dependencies of the HelmRelease become needs on the releases of the
+// same helmfile.
+func (p *HelmfileProvider) GenerateHelmfile(helmRelease *types.HelmRelease) (*Helmfile, error) {
+	if err := validateMode(helmRelease); err != nil {
+		return nil, err
+	}
+	if helmRelease.Spec.Chart.Name == "" {
+		return nil, fmt.Errorf("spec.chart.name is required to generate a helmfile release")
+	}
+
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return nil, err
+	}
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	helmfile := &Helmfile{}
+	release := HelmfileRelease{
+		Name:      helmRelease.ObjectMeta.Name,
+		Namespace: helmRelease.ObjectMeta.Namespace,
+		Chart:     helmRelease.Spec.Chart.Name,
+		Version:   helmRelease.Spec.Chart.Version,
+	}
+
+	if helmRelease.Spec.Chart.Repo != "" {
+		repository := repository(helmRelease.Spec.Chart)
+		helmfile.Repositories = append(helmfile.Repositories, repository)
+		release.Chart = repository.Name + "/" + helmRelease.Spec.Chart.Name
+	}
+
+	if len(chartValues) > 0 {
+		release.Values = []map[string]interface{}{chartValues}
+	}
+
+	for _, dependency := range helmRelease.Spec.DependsOn {
+		namespace := dependency.Namespace
+		if namespace == "" {
+			namespace = helmRelease.ObjectMeta.Namespace
+		}
+		release.Needs = append(release.Needs, namespace+"/"+dependency.Name)
+	}
+
+	if sync := helmRelease.Spec.Sync; sync != nil {
+		release.CreateNamespace = sync.CreateNamespace
+	}
+
+	if err := applyLifecycleSpec(&release, helmRelease.Spec.Lifecycle); err != nil {
+		return nil, err
+	}
+
+	helmfile.Releases = append(helmfile.Releases, release)
+	return helmfile, nil
+}
+
+// UsesFile reports whether the helmfile.yaml is written as a file of the package instead of a ConfigMap
+func (p *HelmfileProvider) UsesFile(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.Helmfile != nil && helmRelease.Spec.Helmfile.Mode == types.HelmfileModeFile
+}
+
+// GenerateConfigMap creates the ConfigMap holding the helmfile.yaml in the namespace of the release
+func (p *HelmfileProvider) GenerateConfigMap(helmRelease *types.HelmRelease, helmfile *Helmfile) (*HelmfileConfigMap, error) {
+	helmfileBytes, err := yaml.Marshal(helmfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal helmfile.yaml: %w", err)
+	}
+
+	configMap := &HelmfileConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigMapName(helmRelease),
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+		Data: map[string]string{HelmfileKey: string(helmfileBytes)},
+	}
+
+	return configMap, nil
+}
+
+// ConfigMapName returns the name of the ConfigMap holding the helmfile.yaml of the release
+func ConfigMapName(helmRelease *types.HelmRelease) string {
+	if helmfile := helmRelease.Spec.Helmfile; helmfile != nil && helmfile.Name != "" {
+		return helmfile.Name
+	}
+	return DefaultName
+}
+
+// FilePath returns the file the helmfile.yaml of the release is written to in the file mode
+func FilePath(helmRelease *types.HelmRelease) string {
+	if helmfile := helmRelease.Spec.Helmfile; helmfile != nil && helmfile.Path != "" {
+		return helmfile.Path
+	}
+	return DefaultPath
+}
+
+// Parse reads a helmfile.yaml
+func Parse(data string) (*Helmfile, error) {
+	var helmfile Helmfile
+	if err := yaml.Unmarshal([]byte(data), &helmfile); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal helmfile.yaml: %w", err)
+	}
+	return &helmfile, nil
+}
+
+// Merge adds the releases and repositories of update to base. A release of update replaces the release of
+// base with the same namespace and name in place, repositories are deduplicated by URL. Repositories no
+// release pulls its chart from any more, e.g. after a release moved to another repository, are dropped.
+func Merge(base, update *Helmfile) *Helmfile {
+	merged := &Helmfile{
+		Repositories: append([]HelmfileRepository(nil), base.Repositories...),
+		Releases:     append([]HelmfileRelease(nil), base.Releases...),
+	}
+
+	for _, repository := range update.Repositories {
+		found := false
+		for i := range merged.Repositories {
+			if merged.Repositories[i].URL == repository.URL && merged.Repositories[i].OCI == repository.OCI {
+				merged.Repositories[i] = repository
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Repositories = append(merged.Repositories, repository)
+		}
+	}
+
+	for _, release := range update.Releases {
+		found := false
+		for i := range merged.Releases {
+			if merged.Releases[i].Namespace == release.Namespace && merged.Releases[i].Name == release.Name {
+				merged.Releases[i] = release
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Releases = append(merged.Releases, release)
+		}
+	}
+
+	merged.Repositories = referencedRepositories(merged.Repositories, merged.Releases)
+	return merged
+}
+
+// Remove removes the release with the given namespace and name from the helmfile, along with the
+// repositories no other release pulls its chart from
+func Remove(base *Helmfile, namespace, name string) *Helmfile {
+	remaining := &Helmfile{}
+	for _, release := range base.Releases {
+		if release.Namespace != namespace || release.Name != name {
+			remaining.Releases = append(remaining.Releases, release)
+		}
+	}
+
+	remaining.Repositories = referencedRepositories(base.Repositories, remaining.Releases)
+	return remaining
+}
+
+// referencedRepositories returns the repositories at least one of the releases pulls its chart from
+func referencedRepositories(repositories []HelmfileRepository, releases []HelmfileRelease) []HelmfileRepository {
+	var referenced []HelmfileRepository
+	for _, repository := range repositories {
+		for _, release := range releases {
+			if strings.HasPrefix(release.Chart, repository.Name+"/") {
+				referenced = append(referenced, repository)
+				break
+			}
+		}
+	}
+	return referenced
+}
+
+// repository maps the chart repository, named after its URL like the FluxCD HelmRepository so releases
+// pulling from the same repository share it. OCI registries are given without their scheme.
+func repository(chart types.ChartSpec) HelmfileRepository {
+	url := strings.TrimSuffix(chart.Repo, "/")
+	repository := HelmfileRepository{URL: url}
+	if strings.HasPrefix(url, "oci://") {
+		repository.URL = strings.TrimPrefix(url, "oci://")
+		repository.OCI = true
+	}
+
+	repository.Name = types.RepositoryName(chart.Repo)
+
+	if auth := chart.Auth; auth != nil {
+		repository.PassCredentials = auth.PassCredentials
+		repository.SkipTLSVerify = auth.InsecureSkipTLSVerify
+	}
+
+	return repository
+}
+
+// validateMode checks the output mode of the helmfile.yaml
+func validateMode(helmRelease *types.HelmRelease) error {
+	if helmRelease.Spec.Helmfile == nil {
+		return nil
+	}
+
+	switch mode := helmRelease.Spec.Helmfile.Mode; mode {
+	case "", types.HelmfileModeConfigMap, types.HelmfileModeFile:
+		return nil
+	default:
+		return fmt.Errorf("unsupported helmfile mode: %s", mode)
+	}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have a helmfile equivalent
+func applyLifecycleSpec(release *HelmfileRelease, lifecycle *types.LifecycleSpec) error {
+	if lifecycle == nil {
+		return nil
+	}
+
+	timeout, err := lifecycle.TimeoutSeconds()
+	if err != nil {
+		return err
+	}
+	release.Timeout = timeout
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package helmfile
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+func TestNewHelmfileProvider(t *testing.T) {
+	provider := NewHelmfileProvider()
+	if provider == nil {
+		t.Error("NewHelmfileProvider returned nil")
+	}
+}
+
+// TestHelmfileProvider_GenerateFromExample tests the helmfile provider using example files
+func TestHelmfileProvider_GenerateFromExample(t *testing.T) {
+	// Load the helmfile example files
+	exampleDir := filepath.Join("..", "..", "examples", "helmfile")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Parse the HelmRelease from the example
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewHelmfileProvider()
+	helmfile, err := provider.GenerateHelmfile(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmfile failed: %v", err)
+	}
+
+	expectedRepositories := []HelmfileRepository{{Name: "helm-github-io-examples", URL: "https://helm.github.io/examples"}}
+	if !reflect.DeepEqual(helmfile.Repositories, expectedRepositories) {
+		t.Errorf("Expected repositories %+v, got %+v", expectedRepositories, helmfile.Repositories)
+	}
+
+	expectedReleases := []HelmfileRelease{{
+		Name:            "my-app",
+		Namespace:       "my-system",
+		Chart:           "helm-github-io-examples/hello-world",
+		Version:         "0.1.0",
+		CreateNamespace: true,
+		Values:          []map[string]interface{}{{"replicaCount": float64(2)}},
+		Needs:           []string{"cert-manager/cert-manager"},
+	}}
+	if !reflect.DeepEqual(helmfile.Releases, expectedReleases) {
+		t.Errorf("Expected releases %+v, got %+v", expectedReleases, helmfile.Releases)
+	}
+
+	if provider.UsesFile(helmRelease) {
+		t.Error("Expected a ConfigMap by default")
+	}
+
+	configMap, err := provider.GenerateConfigMap(helmRelease, helmfile)
+	if err != nil {
+		t.Fatalf("GenerateConfigMap failed: %v", err)
+	}
+
+	if configMap.Name != DefaultName || configMap.Namespace != "my-system" {
+		t.Errorf("Expected the ConfigMap my-system/%s, got %s/%s", DefaultName, configMap.Namespace, configMap.Name)
+	}
+
+	parsed, err := Parse(configMap.Data[HelmfileKey])
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Repositories, helmfile.Repositories) || parsed.Releases[0].Chart != helmfile.Releases[0].Chart {
+		t.Errorf("Expected the ConfigMap to hold the helmfile.yaml, got %+v", parsed)
+	}
+}
+
+// TestHelmfileProvider_GenerateHelmfileSettings tests OCI repositories, repository auth and the lifecycle
+func TestHelmfileProvider_GenerateHelmfileSettings(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart: types.ChartSpec{
+			Name: "podinfo",
+			Repo: "oci://ghcr.io/stefanprodan/charts/",
+			Auth: &types.ChartAuthSpec{PassCredentials: true, InsecureSkipTLSVerify: true},
+		},
+		Lifecycle: &types.LifecycleSpec{Timeout: "10m"},
+		Helmfile:  &types.HelmfileSpec{Mode: types.HelmfileModeFile},
+	}}
+	helmRelease.Name = "podinfo"
+	helmRelease.Namespace = "apps"
+
+	provider := NewHelmfileProvider()
+	helmfile, err := provider.GenerateHelmfile(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmfile failed: %v", err)
+	}
+
+	expected := HelmfileRepository{Name: "ghcr-io-stefanprodan-charts", URL: "ghcr.io/stefanprodan/charts", OCI: true, PassCredentials: true, SkipTLSVerify: true}
+	if !reflect.DeepEqual(helmfile.Repositories, []HelmfileRepository{expected}) {
+		t.Errorf("Expected the repository %+v, got %+v", expected, helmfile.Repositories)
+	}
+
+	release := helmfile.Releases[0]
+	if release.Chart != "ghcr-io-stefanprodan-charts/podinfo" || release.Timeout != 600 || release.Values != nil {
+		t.Errorf("Unexpected release %+v", release)
+	}
+
+	if !provider.UsesFile(helmRelease) || FilePath(helmRelease) != DefaultPath {
+		t.Errorf("Expected the file %s", DefaultPath)
+	}
+
+	helmRelease.Spec.Helmfile.Mode = "directory"
+	if _, err := provider.GenerateHelmfile(helmRelease); err == nil {
+		t.Error("Expected an error for an unsupported mode")
+	}
+}
+
+// TestMerge tests that releases are replaced in place and repositories are deduplicated
+func TestMerge(t *testing.T) {
+	base := &Helmfile{
+		Repositories: []HelmfileRepository{{Name: "jetstack", URL: "https://charts.jetstack.io"}},
+		Releases: []HelmfileRelease{
+			{Name: "cert-manager", Namespace: "cert-manager", Chart: "jetstack/cert-manager", Version: "v1.13.0"},
+			{Name: "my-app", Namespace: "my-system", Chart: "examples/hello-world"},
+		},
+	}
+	update := &Helmfile{
+		Repositories: []HelmfileRepository{{Name: "charts-jetstack-io", URL: "https://charts.jetstack.io"}},
+		Releases:     []HelmfileRelease{{Name: "cert-manager", Namespace: "cert-manager", Chart: "charts-jetstack-io/cert-manager", Version: "v1.14.0"}},
+	}
+
+	merged := Merge(base, update)
+
+	if len(merged.Repositories) != 1 || merged.Repositories[0].Name != "charts-jetstack-io" {
+		t.Errorf("Expected the repository to be replaced, got %+v", merged.Repositories)
+	}
+	if len(merged.Releases) != 2 || merged.Releases[0].Version != "v1.14.0" || merged.Releases[1].Name != "my-app" {
+		t.Errorf("Expected cert-manager to be replaced in place, got %+v", merged.Releases)
+	}
+
+	// The base is left unchanged
+	if base.Releases[0].Version != "v1.13.0" {
+		t.Error("Expected Merge not to modify its arguments")
+	}
+}
+
+// TestMergeDropsUnreferencedRepositories tests that the repository a release moved away from is dropped
+func TestMergeDropsUnreferencedRepositories(t *testing.T) {
+	base := &Helmfile{
+		Repositories: []HelmfileRepository{
+			{Name: "charts-jetstack-io", URL: "https://charts.jetstack.io"},
+			{Name: "helm-github-io-examples", URL: "https://helm.github.io/examples"},
+		},
+		Releases: []HelmfileRelease{
+			{Name: "cert-manager", Namespace: "cert-manager", Chart: "charts-jetstack-io/cert-manager"},
+			{Name: "my-app", Namespace: "my-system", Chart: "helm-github-io-examples/hello-world"},
+		},
+	}
+	update := &Helmfile{
+		Repositories: []HelmfileRepository{{Name: "quay-io-jetstack", URL: "quay.io/jetstack", OCI: true}},
+		Releases:     []HelmfileRelease{{Name: "cert-manager", Namespace: "cert-manager", Chart: "quay-io-jetstack/cert-manager"}},
+	}
+
+	merged := Merge(base, update)
+
+	expected := []HelmfileRepository{
+		{Name: "helm-github-io-examples", URL: "https://helm.github.io/examples"},
+		{Name: "quay-io-jetstack", URL: "quay.io/jetstack", OCI: true},
+	}
+	if !reflect.DeepEqual(merged.Repositories, expected) {
+		t.Errorf("Expected repositories %+v, got %+v", expected, merged.Repositories)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	base := &Helmfile{
+		Repositories: []HelmfileRepository{
+			{Name: "jetstack", URL: "https://charts.jetstack.io"},
+			{Name: "examples", URL: "https://helm.github.io/examples"},
+		},
+		Releases: []HelmfileRelease{
+			{Name: "cert-manager", Namespace: "cert-manager", Chart: "jetstack/cert-manager"},
+			{Name: "my-app", Namespace: "my-system", Chart: "examples/hello-world"},
+			{Name: "other-app", Namespace: "my-system", Chart: "examples/hello-world"},
+		},
+	}
+
+	// The repository stays while another release pulls from it
+	remaining := Remove(base, "my-system", "my-app")
+	if len(remaining.Releases) != 2 || len(remaining.Repositories) != 2 {
+		t.Errorf("Expected my-app to be removed and both repositories kept, got %+v", remaining)
+	}
+
+	remaining = Remove(remaining, "cert-manager", "cert-manager")
+	expected := []HelmfileRepository{{Name: "examples", URL: "https://helm.github.io/examples"}}
+	if len(remaining.Releases) != 1 || !reflect.DeepEqual(remaining.Repositories, expected) {
+		t.Errorf("Expected only other-app and its repository, got %+v", remaining)
+	}
+
+	if len(base.Releases) != 3 {
+		t.Error("Expected Remove not to modify its argument")
+	}
+}
@@ -0,0 +1,231 @@
+package kapp
+
+import (
+	"fmt"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// valuesKey is the key of the values Secret holding the inline values
+const valuesKey = "values.yaml"
+
+// KappApp represents a kapp-controller App resource
+type KappApp struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              KappAppSpec `json:"spec,omitempty"`
+}
+
+// KappAppSpec defines how kapp-controller fetches, templates and deploys the chart
+type KappAppSpec struct {
+	ServiceAccountName string         `json:"serviceAccountName"`
+	SyncPeriod         string         `json:"syncPeriod,omitempty"`
+	Paused             bool           `json:"paused,omitempty"`
+	Fetch              []KappFetch    `json:"fetch"`
+	Template           []KappTemplate `json:"template"`
+	Deploy             []KappDeploy   `json:"deploy"`
+}
+
+// KappFetch is a fetch step of an App
+type KappFetch struct {
+	HelmChart *KappHelmChart `json:"helmChart,omitempty"`
+}
+
+// KappHelmChart fetches a chart from a Helm repository
+type KappHelmChart struct {
+	Name       string                   `json:"name"`
+	Version    string                   `json:"version,omitempty"`
+	Repository *KappHelmChartRepository `json:"repository,omitempty"`
+}
+
+// KappHelmChartRepository is the Helm repository a chart is fetched from
+type KappHelmChartRepository struct {
+	URL       string                      `json:"url"`
+	SecretRef *types.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// KappTemplate is a template step of an App
+type KappTemplate struct {
+	HelmTemplate *KappHelmTemplate `json:"helmTemplate,omitempty"`
+}
+
+// KappHelmTemplate renders the fetched chart with helm template
+type KappHelmTemplate struct {
+	Name       string           `json:"name"`
+	Namespace  string           `json:"namespace"`
+	ValuesFrom []KappValuesFrom `json:"valuesFrom,omitempty"`
+}
+
+// KappValuesFrom references a Secret or ConfigMap whose keys are values files
+type KappValuesFrom struct {
+	SecretRef    *types.LocalObjectReference `json:"secretRef,omitempty"`
+	ConfigMapRef *types.LocalObjectReference `json:"configMapRef,omitempty"`
+}
+
+// KappDeploy is a deploy step of an App
+type KappDeploy struct {
+	Kapp *KappDeployKapp `json:"kapp"`
+}
+
+// KappDeployKapp deploys the rendered resources with kapp
+type KappDeployKapp struct{}
+
+// KappValuesSecret represents the Secret holding the inline values of an App
+type KappValuesSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	StringData        map[string]string `json:"stringData"`
+}
+
+// KappProvider handles the transformation of HelmRelease to kapp-controller App
+type KappProvider struct{}
+
+// NewKappProvider creates a new kapp provider instance
+func NewKappProvider() *KappProvider {
+	return &KappProvider{}
+}
+
+// GenerateApp creates a kapp-controller App resource from a HelmRelease. The chart is fetched from its
+// repository, rendered with helm template and deployed with kapp.
+func (p *KappProvider) GenerateApp(helmRelease *types.HelmRelease) (*KappApp, error) {
+	kapp := helmRelease.Spec.Kapp
+	if kapp == nil || kapp.ServiceAccountName == "" {
+		return nil, fmt.Errorf("spec.kapp.serviceAccountName is required")
+	}
+
+	helmChart := &KappHelmChart{
+		Name:    helmRelease.Spec.Chart.Name,
+		Version: helmRelease.Spec.Chart.Version,
+	}
+	if helmRelease.Spec.Chart.Repo != "" {
+		helmChart.Repository = &KappHelmChartRepository{URL: helmRelease.Spec.Chart.Repo}
+		if auth := helmRelease.Spec.Chart.Auth; auth != nil && auth.SecretName != "" {
+			helmChart.Repository.SecretRef = &types.LocalObjectReference{Name: auth.SecretName}
+		}
+	}
+
+	helmTemplate := &KappHelmTemplate{
+		Name:      helmRelease.ObjectMeta.Name,
+		Namespace: helmRelease.ObjectMeta.Namespace,
+	}
+
+	// Referenced values files come first so the inline values win, like helm applies them
+	for _, ref := range kapp.ValuesFrom {
+		valuesFrom, err := valuesFrom(ref)
+		if err != nil {
+			return nil, err
+		}
+		helmTemplate.ValuesFrom = append(helmTemplate.ValuesFrom, valuesFrom)
+	}
+
+	usesValuesSecret, err := p.UsesValuesSecret(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+	if usesValuesSecret {
+		helmTemplate.ValuesFrom = append(helmTemplate.ValuesFrom, KappValuesFrom{
+			SecretRef: &types.LocalObjectReference{Name: valuesSecretName(helmRelease)},
+		})
+	}
+
+	app := &KappApp{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kappctrl.k14s.io/v1alpha1",
+			Kind:       "App",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helmRelease.ObjectMeta.Name,
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+		Spec: KappAppSpec{
+			ServiceAccountName: kapp.ServiceAccountName,
+			SyncPeriod:         helmRelease.Spec.Chart.Interval,
+			Fetch:              []KappFetch{{HelmChart: helmChart}},
+			Template:           []KappTemplate{{HelmTemplate: helmTemplate}},
+			Deploy:             []KappDeploy{{Kapp: &KappDeployKapp{}}},
+		},
+	}
+
+	applyLifecycleSpec(&app.Spec, helmRelease.Spec.Lifecycle)
+
+	return app, nil
+}
+
+// UsesValuesSecret reports whether the HelmRelease has inline or literal set values, which kapp-controller
+// can only read from a Secret
+func (p *KappProvider) UsesValuesSecret(helmRelease *types.HelmRelease) (bool, error) {
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return false, err
+	}
+	if len(helmRelease.Spec.Values) > 0 {
+		return true, nil
+	}
+	for _, set := range helmRelease.Spec.Set {
+		if set.ValueFrom == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GenerateValuesSecret creates the Secret holding the inline values of the App, with the literal set
+// values merged into them. Values read from a Secret or ConfigMap have no kapp-controller equivalent
+// and are skipped.
+func (p *KappProvider) GenerateValuesSecret(helmRelease *types.HelmRelease) (*KappValuesSecret, error) {
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesBytes, err := yaml.Marshal(chartValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal values: %w", err)
+	}
+
+	secret := &KappValuesSecret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      valuesSecretName(helmRelease),
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+		StringData: map[string]string{valuesKey: string(valuesBytes)},
+	}
+
+	return secret, nil
+}
+
+// valuesSecretName returns the name of the Secret holding the inline values of the release
+func valuesSecretName(helmRelease *types.HelmRelease) string {
+	return helmRelease.ObjectMeta.Name + "-kapp-values"
+}
+
+// valuesFrom maps a reference to a values Secret or ConfigMap
+func valuesFrom(ref types.ValuesReference) (KappValuesFrom, error) {
+	if ref.Name == "" {
+		return KappValuesFrom{}, fmt.Errorf("values reference without a name")
+	}
+
+	switch ref.Kind {
+	case "Secret":
+		return KappValuesFrom{SecretRef: &types.LocalObjectReference{Name: ref.Name}}, nil
+	case "ConfigMap":
+		return KappValuesFrom{ConfigMapRef: &types.LocalObjectReference{Name: ref.Name}}, nil
+	default:
+		return KappValuesFrom{}, fmt.Errorf("unsupported values reference kind: %s", ref.Kind)
+	}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have a kapp-controller equivalent
+func applyLifecycleSpec(spec *KappAppSpec, lifecycle *types.LifecycleSpec) {
+	if lifecycle == nil {
+		return
+	}
+
+	spec.Paused = lifecycle.Suspend
+}
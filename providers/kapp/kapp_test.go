@@ -0,0 +1,143 @@
+package kapp
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+func TestNewKappProvider(t *testing.T) {
+	provider := NewKappProvider()
+	if provider == nil {
+		t.Error("NewKappProvider returned nil")
+	}
+}
+
+// TestKappProvider_GenerateAppFromExample tests the kapp provider using example files
+func TestKappProvider_GenerateAppFromExample(t *testing.T) {
+	// Load the kapp example files
+	exampleDir := filepath.Join("..", "..", "examples", "kapp")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Parse the HelmRelease from the example
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewKappProvider()
+	app, err := provider.GenerateApp(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApp failed: %v", err)
+	}
+
+	if app.APIVersion != "kappctrl.k14s.io/v1alpha1" || app.Kind != "App" {
+		t.Errorf("Expected a kappctrl.k14s.io/v1alpha1 App, got %s %s", app.APIVersion, app.Kind)
+	}
+
+	if app.Name != "my-app" || app.Namespace != "my-system" {
+		t.Errorf("Expected my-system/my-app, got %s/%s", app.Namespace, app.Name)
+	}
+
+	if app.Spec.ServiceAccountName != "my-app-deployer" || app.Spec.SyncPeriod != "10m" {
+		t.Errorf("Unexpected service account '%s' or sync period '%s'", app.Spec.ServiceAccountName, app.Spec.SyncPeriod)
+	}
+
+	expectedChart := &KappHelmChart{
+		Name:       "hello-world",
+		Version:    "0.1.0",
+		Repository: &KappHelmChartRepository{URL: "https://helm.github.io/examples"},
+	}
+	if len(app.Spec.Fetch) != 1 || !reflect.DeepEqual(app.Spec.Fetch[0].HelmChart, expectedChart) {
+		t.Errorf("Expected fetch %+v, got %+v", expectedChart, app.Spec.Fetch)
+	}
+
+	// The referenced ConfigMap comes before the inline values
+	expectedValuesFrom := []KappValuesFrom{
+		{ConfigMapRef: &types.LocalObjectReference{Name: "my-app-values"}},
+		{SecretRef: &types.LocalObjectReference{Name: "my-app-kapp-values"}},
+	}
+	if len(app.Spec.Template) != 1 || !reflect.DeepEqual(app.Spec.Template[0].HelmTemplate.ValuesFrom, expectedValuesFrom) {
+		t.Errorf("Expected valuesFrom %+v, got %+v", expectedValuesFrom, app.Spec.Template)
+	}
+
+	if len(app.Spec.Deploy) != 1 || app.Spec.Deploy[0].Kapp == nil {
+		t.Errorf("Expected a single kapp deploy step, got %+v", app.Spec.Deploy)
+	}
+}
+
+// TestKappProvider_GenerateValuesSecret tests that the inline and literal set values end up in the values Secret
+func TestKappProvider_GenerateValuesSecret(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Values: map[string]interface{}{"replicaCount": 2},
+		Set: []types.SetValue{
+			{Name: "image.tag", Value: "1.2.3"},
+			{Name: "auth.password", ValueFrom: &types.ValueSource{SecretKeyRef: &types.KeySelector{Name: "db", Key: "password"}}},
+		},
+	}}
+	helmRelease.Name = "my-app"
+	helmRelease.Namespace = "my-system"
+
+	provider := NewKappProvider()
+	secret, err := provider.GenerateValuesSecret(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateValuesSecret failed: %v", err)
+	}
+
+	if secret.Name != "my-app-kapp-values" || secret.Namespace != "my-system" {
+		t.Errorf("Expected my-system/my-app-kapp-values, got %s/%s", secret.Namespace, secret.Name)
+	}
+
+	expected := "image:\n  tag: 1.2.3\nreplicaCount: 2\n"
+	if secret.StringData["values.yaml"] != expected {
+		t.Errorf("Expected values %q, got %q", expected, secret.StringData["values.yaml"])
+	}
+}
+
+// TestKappProvider_GenerateAppSettings tests the repository credentials, lifecycle and validation
+func TestKappProvider_GenerateAppSettings(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart: types.ChartSpec{
+			Name: "hello-world",
+			Repo: "https://helm.github.io/examples",
+			Auth: &types.ChartAuthSpec{SecretName: "repo-auth"},
+		},
+		Lifecycle: &types.LifecycleSpec{Suspend: true},
+	}}
+
+	provider := NewKappProvider()
+	if _, err := provider.GenerateApp(helmRelease); err == nil {
+		t.Error("Expected an error without spec.kapp.serviceAccountName")
+	}
+
+	helmRelease.Spec.Kapp = &types.KappSpec{ServiceAccountName: "deployer"}
+	app, err := provider.GenerateApp(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateApp failed: %v", err)
+	}
+
+	if !app.Spec.Paused {
+		t.Errorf("Expected a suspended release to pause the App")
+	}
+
+	repository := app.Spec.Fetch[0].HelmChart.Repository
+	if repository == nil || !reflect.DeepEqual(repository.SecretRef, &types.LocalObjectReference{Name: "repo-auth"}) {
+		t.Errorf("Expected the repository to use the Secret repo-auth, got %+v", repository)
+	}
+
+	// Without values there is nothing to put in a Secret
+	if uses, err := provider.UsesValuesSecret(helmRelease); err != nil || uses {
+		t.Errorf("Expected no values Secret, got %v, %v", uses, err)
+	}
+
+	helmRelease.Spec.Kapp.ValuesFrom = []types.ValuesReference{{Kind: "Deployment", Name: "values"}}
+	if _, err := provider.GenerateApp(helmRelease); err == nil {
+		t.Error("Expected an error for a values reference which is not a Secret or ConfigMap")
+	}
+}
@@ -0,0 +1,150 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LocalConfigAnnotation keeps kpt from applying the generated kustomize configuration to the cluster
+const LocalConfigAnnotation = "config.kubernetes.io/local-config"
+
+// KustomizeHelmChart is a chart inflated by kustomize with helm template
+type KustomizeHelmChart struct {
+	Name         string                 `json:"name"`
+	Version      string                 `json:"version,omitempty"`
+	Repo         string                 `json:"repo,omitempty"`
+	ReleaseName  string                 `json:"releaseName"`
+	Namespace    string                 `json:"namespace,omitempty"`
+	ValuesFile   string                 `json:"valuesFile,omitempty"`
+	ValuesInline map[string]interface{} `json:"valuesInline,omitempty"`
+	IncludeCRDs  bool                   `json:"includeCRDs,omitempty"`
+	APIVersions  []string               `json:"apiVersions,omitempty"`
+}
+
+// KustomizeGenerator represents the configuration of the kustomize builtin HelmChartInflationGenerator
+type KustomizeGenerator struct {
+	metav1.TypeMeta    `json:",inline"`
+	metav1.ObjectMeta  `json:"metadata,omitempty"`
+	KustomizeHelmChart `json:",inline"`
+}
+
+// Kustomization represents a kustomization fragment inflating charts
+type Kustomization struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	HelmCharts        []KustomizeHelmChart `json:"helmCharts"`
+}
+
+// KustomizeProvider handles the transformation of HelmRelease to kustomize helm chart configuration
+type KustomizeProvider struct{}
+
+// NewKustomizeProvider creates a new kustomize provider instance
+func NewKustomizeProvider() *KustomizeProvider {
+	return &KustomizeProvider{}
+}
+
+// UsesHelmCharts reports whether the HelmRelease asks for a helmCharts fragment instead of a generator
+func (p *KustomizeProvider) UsesHelmCharts(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.Kustomize != nil && helmRelease.Spec.Kustomize.Mode == types.KustomizeModeHelmCharts
+}
+
+// GenerateGenerator creates a HelmChartInflationGenerator configuration, listed in the generators of a
+// kustomization to have kustomize itself inflate the chart
+func (p *KustomizeProvider) GenerateGenerator(helmRelease *types.HelmRelease) (*KustomizeGenerator, error) {
+	helmChart, err := helmChart(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	generator := &KustomizeGenerator{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "builtin",
+			Kind:       "HelmChartInflationGenerator",
+		},
+		ObjectMeta:         objectMeta(helmRelease),
+		KustomizeHelmChart: *helmChart,
+	}
+
+	return generator, nil
+}
+
+// GenerateKustomization creates a kustomization fragment with the chart in its helmCharts, to be merged
+// into the kustomization inflating the chart
+func (p *KustomizeProvider) GenerateKustomization(helmRelease *types.HelmRelease) (*Kustomization, error) {
+	helmChart, err := helmChart(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	kustomization := &Kustomization{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kustomize.config.k8s.io/v1beta1",
+			Kind:       "Kustomization",
+		},
+		ObjectMeta: objectMeta(helmRelease),
+		HelmCharts: []KustomizeHelmChart{*helmChart},
+	}
+
+	return kustomization, nil
+}
+
+// helmChart builds the chart kustomize inflates. The values, with the literal set values merged in, are
+// inline values applied over the values file.
+func helmChart(helmRelease *types.HelmRelease) (*KustomizeHelmChart, error) {
+	if err := validateMode(helmRelease); err != nil {
+		return nil, err
+	}
+	if helmRelease.Spec.Chart.Name == "" {
+		return nil, fmt.Errorf("spec.chart.name is required to inflate a chart with kustomize")
+	}
+
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return nil, err
+	}
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	helmChart := &KustomizeHelmChart{
+		Name:         helmRelease.Spec.Chart.Name,
+		Version:      helmRelease.Spec.Chart.Version,
+		Repo:         helmRelease.Spec.Chart.Repo,
+		ReleaseName:  helmRelease.ObjectMeta.Name,
+		Namespace:    helmRelease.ObjectMeta.Namespace,
+		ValuesInline: chartValues,
+	}
+
+	if kustomize := helmRelease.Spec.Kustomize; kustomize != nil {
+		helmChart.ValuesFile = kustomize.ValuesFile
+		helmChart.IncludeCRDs = kustomize.IncludeCRDs
+		helmChart.APIVersions = kustomize.APIVersions
+	}
+
+	return helmChart, nil
+}
+
+// objectMeta names the generated configuration after the release and marks it as local configuration
+func objectMeta(helmRelease *types.HelmRelease) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        helmRelease.ObjectMeta.Name,
+		Annotations: map[string]string{LocalConfigAnnotation: "true"},
+	}
+}
+
+// validateMode checks the output mode of the kustomize configuration
+func validateMode(helmRelease *types.HelmRelease) error {
+	if helmRelease.Spec.Kustomize == nil {
+		return nil
+	}
+
+	switch mode := helmRelease.Spec.Kustomize.Mode; mode {
+	case "", types.KustomizeModeGenerator, types.KustomizeModeHelmCharts:
+		return nil
+	default:
+		return fmt.Errorf("unsupported kustomize mode: %s", mode)
+	}
+}
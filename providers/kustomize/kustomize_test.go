@@ -0,0 +1,106 @@
+package kustomize
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+)
+
+func TestNewKustomizeProvider(t *testing.T) {
+	provider := NewKustomizeProvider()
+	if provider == nil {
+		t.Error("NewKustomizeProvider returned nil")
+	}
+}
+
+// TestKustomizeProvider_GenerateFromExample tests the kustomize provider using example files
+func TestKustomizeProvider_GenerateFromExample(t *testing.T) {
+	// Load the kustomize example files
+	exampleDir := filepath.Join("..", "..", "examples", "kustomize")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Parse the HelmRelease from the example
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewKustomizeProvider()
+	if provider.UsesHelmCharts(helmRelease) {
+		t.Error("Expected a generator by default")
+	}
+
+	generator, err := provider.GenerateGenerator(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateGenerator failed: %v", err)
+	}
+
+	if generator.APIVersion != "builtin" || generator.Kind != "HelmChartInflationGenerator" {
+		t.Errorf("Expected a builtin HelmChartInflationGenerator, got %s %s", generator.APIVersion, generator.Kind)
+	}
+
+	if generator.Annotations[LocalConfigAnnotation] != "true" {
+		t.Error("Expected the generator to be local configuration")
+	}
+
+	expected := KustomizeHelmChart{
+		Name:         "hello-world",
+		Version:      "0.1.0",
+		Repo:         "https://helm.github.io/examples",
+		ReleaseName:  "my-app",
+		Namespace:    "my-system",
+		ValuesFile:   "values.yaml",
+		ValuesInline: map[string]interface{}{"replicaCount": float64(2)},
+		IncludeCRDs:  true,
+		APIVersions:  []string{"monitoring.coreos.com/v1"},
+	}
+	if !reflect.DeepEqual(generator.KustomizeHelmChart, expected) {
+		t.Errorf("Expected the chart %+v, got %+v", expected, generator.KustomizeHelmChart)
+	}
+}
+
+// TestKustomizeProvider_GenerateKustomization tests the helmCharts fragment and the mode validation
+func TestKustomizeProvider_GenerateKustomization(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart:     types.ChartSpec{Name: "podinfo", Repo: "oci://ghcr.io/stefanprodan/charts"},
+		Set:       []types.SetValue{{Name: "replicaCount", Value: "3"}},
+		Kustomize: &types.KustomizeSpec{Mode: types.KustomizeModeHelmCharts},
+	}}
+	helmRelease.Name = "podinfo"
+	helmRelease.Namespace = "apps"
+
+	provider := NewKustomizeProvider()
+	if !provider.UsesHelmCharts(helmRelease) {
+		t.Fatal("Expected a helmCharts fragment")
+	}
+
+	kustomization, err := provider.GenerateKustomization(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateKustomization failed: %v", err)
+	}
+
+	if kustomization.Kind != "Kustomization" || len(kustomization.HelmCharts) != 1 {
+		t.Fatalf("Expected a Kustomization with one chart, got %+v", kustomization)
+	}
+
+	helmChart := kustomization.HelmCharts[0]
+	if helmChart.Repo != "oci://ghcr.io/stefanprodan/charts" || helmChart.ReleaseName != "podinfo" || helmChart.Namespace != "apps" {
+		t.Errorf("Unexpected chart %+v", helmChart)
+	}
+
+	// Literal set values are merged into the inline values
+	if helmChart.ValuesInline["replicaCount"] != int64(3) {
+		t.Errorf("Expected replicaCount 3 in the inline values, got %v", helmChart.ValuesInline)
+	}
+
+	helmRelease.Spec.Kustomize.Mode = "overlay"
+	if _, err := provider.GenerateKustomization(helmRelease); err == nil {
+		t.Error("Expected an error for an unsupported mode")
+	}
+}
@@ -0,0 +1,219 @@
+package ocm
+
+import (
+	"fmt"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OCMChannel represents an Open Cluster Management Channel resource
+type OCMChannel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              OCMChannelSpec `json:"spec,omitempty"`
+}
+
+// OCMChannelSpec defines the Helm repository a Channel serves charts from
+type OCMChannelSpec struct {
+	Type               string `json:"type"`
+	Pathname           string `json:"pathname"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// OCMSubscription represents an Open Cluster Management Subscription resource
+type OCMSubscription struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              OCMSubscriptionSpec `json:"spec,omitempty"`
+}
+
+// OCMSubscriptionSpec defines the chart a Subscription deploys and where
+type OCMSubscriptionSpec struct {
+	Channel          string               `json:"channel"`
+	Name             string               `json:"name"`
+	PackageFilter    *OCMPackageFilter    `json:"packageFilter,omitempty"`
+	PackageOverrides []OCMPackageOverride `json:"packageOverrides,omitempty"`
+	Placement        OCMPlacementRule     `json:"placement"`
+}
+
+// OCMPackageFilter selects the chart version
+type OCMPackageFilter struct {
+	Version string `json:"version,omitempty"`
+}
+
+// OCMPackageOverride customizes the release of a chart
+type OCMPackageOverride struct {
+	PackageName      string                   `json:"packageName"`
+	PackageAlias     string                   `json:"packageAlias,omitempty"`
+	PackageOverrides []map[string]interface{} `json:"packageOverrides,omitempty"`
+}
+
+// OCMPlacementRule references the Placement selecting the managed clusters
+type OCMPlacementRule struct {
+	PlacementRef OCMPlacementReference `json:"placementRef"`
+}
+
+// OCMPlacementReference references a Placement in the namespace of the Subscription
+type OCMPlacementReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// OCMPlacement represents an Open Cluster Management Placement resource
+type OCMPlacement struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              OCMPlacementSpec `json:"spec,omitempty"`
+}
+
+// OCMPlacementSpec selects managed clusters by label
+type OCMPlacementSpec struct {
+	Predicates []OCMClusterPredicate `json:"predicates"`
+}
+
+// OCMClusterPredicate is a set of cluster requirements
+type OCMClusterPredicate struct {
+	RequiredClusterSelector OCMClusterSelector `json:"requiredClusterSelector"`
+}
+
+// OCMClusterSelector selects managed clusters by label
+type OCMClusterSelector struct {
+	LabelSelector metav1.LabelSelector `json:"labelSelector"`
+}
+
+// OCMProvider handles the transformation of HelmRelease to Open Cluster Management resources
+type OCMProvider struct{}
+
+// NewOCMProvider creates a new Open Cluster Management provider instance
+func NewOCMProvider() *OCMProvider {
+	return &OCMProvider{}
+}
+
+// GenerateSubscription creates a Subscription deploying the chart from the Channel of its repository
+// to the managed clusters selected by the Placement. The values, with the literal set values merged in,
+// override the spec of the release.
+func (p *OCMProvider) GenerateSubscription(helmRelease *types.HelmRelease) (*OCMSubscription, error) {
+	if helmRelease.Spec.Chart.Name == "" {
+		return nil, fmt.Errorf("spec.chart.name is required to generate a Subscription")
+	}
+
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return nil, err
+	}
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	override := OCMPackageOverride{
+		PackageName:  helmRelease.Spec.Chart.Name,
+		PackageAlias: helmRelease.ObjectMeta.Name,
+	}
+	if len(chartValues) > 0 {
+		override.PackageOverrides = []map[string]interface{}{{"path": "spec", "value": chartValues}}
+	}
+
+	subscription := &OCMSubscription{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps.open-cluster-management.io/v1",
+			Kind:       "Subscription",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helmRelease.ObjectMeta.Name,
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+		Spec: OCMSubscriptionSpec{
+			Channel:          helmRelease.ObjectMeta.Namespace + "/" + channelName(helmRelease),
+			Name:             helmRelease.Spec.Chart.Name,
+			PackageOverrides: []OCMPackageOverride{override},
+			Placement: OCMPlacementRule{PlacementRef: OCMPlacementReference{
+				Kind: "Placement",
+				Name: placementName(helmRelease),
+			}},
+		},
+	}
+
+	if helmRelease.Spec.Chart.Version != "" {
+		subscription.Spec.PackageFilter = &OCMPackageFilter{Version: helmRelease.Spec.Chart.Version}
+	}
+
+	return subscription, nil
+}
+
+// GenerateChannel creates the Channel serving the charts of the chart repository
+func (p *OCMProvider) GenerateChannel(helmRelease *types.HelmRelease) (*OCMChannel, error) {
+	if helmRelease.Spec.Chart.Repo == "" {
+		return nil, fmt.Errorf("spec.chart.repo is required to generate a Channel")
+	}
+
+	channel := &OCMChannel{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps.open-cluster-management.io/v1",
+			Kind:       "Channel",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      channelName(helmRelease),
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+		Spec: OCMChannelSpec{
+			Type:     "HelmRepo",
+			Pathname: helmRelease.Spec.Chart.Repo,
+		},
+	}
+
+	if auth := helmRelease.Spec.Chart.Auth; auth != nil {
+		channel.Spec.InsecureSkipVerify = auth.InsecureSkipTLSVerify
+	}
+
+	return channel, nil
+}
+
+// UsesPlacement reports whether a Placement is generated for the HelmRelease
+func (p *OCMProvider) UsesPlacement(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.OCM == nil || helmRelease.Spec.OCM.Placement == ""
+}
+
+// GeneratePlacement creates the Placement selecting the managed clusters matching the cluster selector of
+// the HelmRelease. Only the clusters of the ManagedClusterSets bound to the namespace of the release can
+// be selected.
+func (p *OCMProvider) GeneratePlacement(helmRelease *types.HelmRelease) (*OCMPlacement, error) {
+	if !p.UsesPlacement(helmRelease) {
+		return nil, fmt.Errorf("spec.ocm.placement names an existing Placement")
+	}
+	if helmRelease.Spec.ClusterSelector == nil {
+		return nil, fmt.Errorf("spec.clusterSelector or spec.ocm.placement is required to select the managed clusters")
+	}
+
+	placement := &OCMPlacement{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "cluster.open-cluster-management.io/v1beta1",
+			Kind:       "Placement",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      placementName(helmRelease),
+			Namespace: helmRelease.ObjectMeta.Namespace,
+		},
+		Spec: OCMPlacementSpec{
+			Predicates: []OCMClusterPredicate{{
+				RequiredClusterSelector: OCMClusterSelector{LabelSelector: *helmRelease.Spec.ClusterSelector},
+			}},
+		},
+	}
+
+	return placement, nil
+}
+
+// channelName returns the name of the Channel of the release
+func channelName(helmRelease *types.HelmRelease) string {
+	return helmRelease.ObjectMeta.Name + "-helm"
+}
+
+// placementName returns the Placement the Subscription of the release uses
+func placementName(helmRelease *types.HelmRelease) string {
+	if helmRelease.Spec.OCM != nil && helmRelease.Spec.OCM.Placement != "" {
+		return helmRelease.Spec.OCM.Placement
+	}
+	return helmRelease.ObjectMeta.Name
+}
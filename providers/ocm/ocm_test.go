@@ -0,0 +1,123 @@
+package ocm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewOCMProvider(t *testing.T) {
+	provider := NewOCMProvider()
+	if provider == nil {
+		t.Error("NewOCMProvider returned nil")
+	}
+}
+
+// TestOCMProvider_GenerateFromExample tests the Open Cluster Management provider using example files
+func TestOCMProvider_GenerateFromExample(t *testing.T) {
+	// Load the ocm example files
+	exampleDir := filepath.Join("..", "..", "examples", "ocm")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Parse the HelmRelease from the example
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewOCMProvider()
+	subscription, err := provider.GenerateSubscription(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateSubscription failed: %v", err)
+	}
+
+	if subscription.APIVersion != "apps.open-cluster-management.io/v1" || subscription.Kind != "Subscription" {
+		t.Errorf("Expected an apps.open-cluster-management.io/v1 Subscription, got %s %s", subscription.APIVersion, subscription.Kind)
+	}
+
+	channel, err := provider.GenerateChannel(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateChannel failed: %v", err)
+	}
+
+	// The Subscription references the Channel by namespace and name
+	if subscription.Spec.Channel != channel.Namespace+"/"+channel.Name {
+		t.Errorf("Expected the Subscription to use the Channel %s/%s, got '%s'", channel.Namespace, channel.Name, subscription.Spec.Channel)
+	}
+
+	if channel.Spec.Type != "HelmRepo" || channel.Spec.Pathname != "https://helm.github.io/examples" {
+		t.Errorf("Unexpected Channel %+v", channel.Spec)
+	}
+
+	expectedOverrides := []OCMPackageOverride{{
+		PackageName:      "hello-world",
+		PackageAlias:     "my-app",
+		PackageOverrides: []map[string]interface{}{{"path": "spec", "value": map[string]interface{}{"replicaCount": float64(2)}}},
+	}}
+	if !reflect.DeepEqual(subscription.Spec.PackageOverrides, expectedOverrides) {
+		t.Errorf("Expected package overrides %+v, got %+v", expectedOverrides, subscription.Spec.PackageOverrides)
+	}
+
+	if subscription.Spec.PackageFilter == nil || subscription.Spec.PackageFilter.Version != "0.1.0" {
+		t.Errorf("Expected the version 0.1.0 to be selected, got %+v", subscription.Spec.PackageFilter)
+	}
+
+	if !provider.UsesPlacement(helmRelease) {
+		t.Fatal("Expected a Placement without spec.ocm.placement")
+	}
+
+	placement, err := provider.GeneratePlacement(helmRelease)
+	if err != nil {
+		t.Fatalf("GeneratePlacement failed: %v", err)
+	}
+
+	if subscription.Spec.Placement.PlacementRef.Name != placement.Name || placement.Namespace != "my-system" {
+		t.Errorf("Expected the Subscription to use the Placement %s/%s, got %+v", placement.Namespace, placement.Name, subscription.Spec.Placement)
+	}
+
+	expectedSelector := metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}}
+	if len(placement.Spec.Predicates) != 1 || !reflect.DeepEqual(placement.Spec.Predicates[0].RequiredClusterSelector.LabelSelector, expectedSelector) {
+		t.Errorf("Expected predicates selecting %+v, got %+v", expectedSelector, placement.Spec.Predicates)
+	}
+}
+
+// TestOCMProvider_GenerateSubscriptionSettings tests an existing Placement and the deployment modes
+func TestOCMProvider_GenerateSubscriptionSettings(t *testing.T) {
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart: types.ChartSpec{Name: "hello-world", Repo: "https://helm.github.io/examples"},
+		OCM:   &types.OCMSpec{Placement: "all-clusters"},
+	}}
+	helmRelease.Name = "my-app"
+	helmRelease.Namespace = "my-system"
+
+	provider := NewOCMProvider()
+	if provider.UsesPlacement(helmRelease) {
+		t.Error("Expected no Placement with spec.ocm.placement")
+	}
+
+	subscription, err := provider.GenerateSubscription(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateSubscription failed: %v", err)
+	}
+
+	if subscription.Spec.Placement.PlacementRef.Name != "all-clusters" {
+		t.Errorf("Expected the Placement all-clusters, got '%s'", subscription.Spec.Placement.PlacementRef.Name)
+	}
+
+	// Without values the release keeps the chart defaults
+	if subscription.Spec.PackageOverrides[0].PackageOverrides != nil || subscription.Spec.PackageFilter != nil {
+		t.Errorf("Expected no value overrides or version filter, got %+v", subscription.Spec)
+	}
+
+	helmRelease.Spec.OCM = nil
+	if _, err := provider.GeneratePlacement(helmRelease); err == nil {
+		t.Error("Expected an error without spec.clusterSelector")
+	}
+}
@@ -1,10 +1,17 @@
 package rancher
 
 import (
+	"fmt"
+
 	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
 )
 
+// PackagedChartNamespace is where K3s and RKE2 install the charts they ship with
+const PackagedChartNamespace = "kube-system"
+
 // RancherHelmChart represents a Rancher K3s HelmChart resource
 type RancherHelmChart struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -14,8 +21,37 @@ type RancherHelmChart struct {
 
 // RancherHelmChartSpec defines the desired state of Rancher K3s HelmChart
 type RancherHelmChartSpec struct {
-	// For phase 1, we'll keep this minimal
-	// Full spec will be added in future phases
+	Chart                 string                      `json:"chart"`
+	Repo                  string                      `json:"repo,omitempty"`
+	Version               string                      `json:"version,omitempty"`
+	TargetNamespace       string                      `json:"targetNamespace,omitempty"`
+	CreateNamespace       bool                        `json:"createNamespace,omitempty"`
+	ValuesContent         string                      `json:"valuesContent,omitempty"`
+	BackOffLimit          *int32                      `json:"backOffLimit,omitempty"`
+	Timeout               string                      `json:"timeout,omitempty"`
+	FailurePolicy         string                      `json:"failurePolicy,omitempty"`
+	JobImage              string                      `json:"jobImage,omitempty"`
+	Bootstrap             bool                        `json:"bootstrap,omitempty"`
+	AuthSecret            *types.LocalObjectReference `json:"authSecret,omitempty"`
+	AuthPassCredentials   bool                        `json:"authPassCredentials,omitempty"`
+	InsecureSkipTLSVerify bool                        `json:"insecureSkipTLSVerify,omitempty"`
+	RepoCAConfigMap       *types.LocalObjectReference `json:"repoCAConfigMap,omitempty"`
+	DockerRegistrySecret  *types.LocalObjectReference `json:"dockerRegistrySecret,omitempty"`
+	PodSecurityContext    map[string]interface{}      `json:"podSecurityContext,omitempty"`
+}
+
+// RancherHelmChartConfig represents a Rancher K3s HelmChartConfig resource, which customizes a HelmChart
+// of the same name and namespace
+type RancherHelmChartConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              RancherHelmChartConfigSpec `json:"spec,omitempty"`
+}
+
+// RancherHelmChartConfigSpec defines the desired state of Rancher K3s HelmChartConfig
+type RancherHelmChartConfigSpec struct {
+	ValuesContent string `json:"valuesContent,omitempty"`
+	FailurePolicy string `json:"failurePolicy,omitempty"`
 }
 
 // RancherProvider handles the transformation of HelmRelease to Rancher K3s HelmChart
@@ -26,8 +62,20 @@ func NewRancherProvider() *RancherProvider {
 	return &RancherProvider{}
 }
 
-// GenerateHelmChart creates a Rancher K3s HelmChart resource from a HelmRelease
+// GenerateHelmChart creates a Rancher K3s HelmChart resource from a HelmRelease. helm-controller passes
+// the set field to helm as --set-string, so the literal set values are merged into the values to keep
+// their type.
 func (p *RancherProvider) GenerateHelmChart(helmRelease *types.HelmRelease) (*RancherHelmChart, error) {
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesContent, err := marshalValues(chartValues)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the Rancher K3s HelmChart with basic structure
 	helmChart := &RancherHelmChart{
 		TypeMeta: metav1.TypeMeta{
@@ -39,9 +87,157 @@ func (p *RancherProvider) GenerateHelmChart(helmRelease *types.HelmRelease) (*Ra
 			Namespace: helmRelease.ObjectMeta.Namespace,
 		},
 		Spec: RancherHelmChartSpec{
-			// Minimal spec for phase 1
+			Chart:           helmRelease.Spec.Chart.Name,
+			Repo:            helmRelease.Spec.Chart.Repo,
+			Version:         helmRelease.Spec.Chart.Version,
+			TargetNamespace: helmRelease.ObjectMeta.Namespace,
+			ValuesContent:   valuesContent,
 		},
 	}
 
+	applySyncSpec(&helmChart.Spec, helmRelease.Spec.Sync)
+	applyLifecycleSpec(&helmChart.Spec, helmRelease.Spec.Lifecycle)
+
+	if auth := helmRelease.Spec.Chart.Auth; auth != nil {
+		helmChart.Spec.AuthSecret = types.LocalReference(auth.SecretName)
+		helmChart.Spec.RepoCAConfigMap = types.LocalReference(auth.CAConfigMapName)
+		helmChart.Spec.AuthPassCredentials = auth.PassCredentials
+		helmChart.Spec.InsecureSkipTLSVerify = auth.InsecureSkipTLSVerify
+	}
+
+	failurePolicy, err := failurePolicy(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+	helmChart.Spec.FailurePolicy = failurePolicy
+
+	applyRancherSpec(&helmChart.Spec, helmRelease.Spec.Rancher)
+
 	return helmChart, nil
-}
\ No newline at end of file
+}
+
+// UsesHelmChartConfig reports whether the HelmRelease customizes a packaged chart instead of installing one
+func (p *RancherProvider) UsesHelmChartConfig(helmRelease *types.HelmRelease) bool {
+	return helmRelease.Spec.Rancher != nil && helmRelease.Spec.Rancher.ChartConfig
+}
+
+// GenerateHelmChartConfig creates a Rancher K3s HelmChartConfig customizing a chart packaged with K3s or
+// RKE2, such as traefik or coredns. Packaged charts live in kube-system and the HelmChartConfig has to
+// match their name and namespace. Only values reach the packaged chart, so literal set values are merged
+// into them.
+func (p *RancherProvider) GenerateHelmChartConfig(helmRelease *types.HelmRelease) (*RancherHelmChartConfig, error) {
+	if helmRelease.Spec.Chart.Name == "" {
+		return nil, fmt.Errorf("spec.chart.name is required to customize a packaged chart")
+	}
+
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesContent, err := marshalValues(chartValues)
+	if err != nil {
+		return nil, err
+	}
+
+	failurePolicy, err := failurePolicy(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	helmChartConfig := &RancherHelmChartConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "helm.cattle.io/v1",
+			Kind:       "HelmChartConfig",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      helmRelease.Spec.Chart.Name,
+			Namespace: PackagedChartNamespace,
+		},
+		Spec: RancherHelmChartConfigSpec{
+			ValuesContent: valuesContent,
+			FailurePolicy: failurePolicy,
+		},
+	}
+
+	return helmChartConfig, nil
+}
+
+// applyRancherSpec sets the helm-controller fields which have no portable equivalent
+func applyRancherSpec(spec *RancherHelmChartSpec, rancher *types.RancherSpec) {
+	if rancher == nil {
+		return
+	}
+
+	spec.JobImage = rancher.JobImage
+	spec.Bootstrap = rancher.Bootstrap
+	spec.DockerRegistrySecret = types.LocalReference(rancher.DockerRegistrySecret)
+	spec.PodSecurityContext = rancher.PodSecurityContext
+	if rancher.BackOffLimit != nil {
+		spec.BackOffLimit = rancher.BackOffLimit
+	}
+}
+
+// failurePolicy returns the validated failure policy of the HelmRelease
+func failurePolicy(helmRelease *types.HelmRelease) (string, error) {
+	if helmRelease.Spec.Rancher == nil {
+		return "", nil
+	}
+
+	switch policy := helmRelease.Spec.Rancher.FailurePolicy; policy {
+	case "", "reinstall", "abort":
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported failure policy: %s", policy)
+	}
+}
+
+// applySyncSpec maps the portable sync settings onto the closest helm-controller equivalents. The
+// helm-controller always reconciles automatically, the other settings have no equivalent.
+func applySyncSpec(spec *RancherHelmChartSpec, sync *types.SyncSpec) {
+	if sync == nil {
+		return
+	}
+
+	spec.CreateNamespace = sync.CreateNamespace
+
+	if sync.Retry != nil && sync.Retry.Limit > 0 {
+		limit := int32(sync.Retry.Limit)
+		spec.BackOffLimit = &limit
+	}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have a helm-controller equivalent. Installs
+// and upgrades run in the same job, so the larger of their retries becomes the job backoff limit.
+func applyLifecycleSpec(spec *RancherHelmChartSpec, lifecycle *types.LifecycleSpec) {
+	if lifecycle == nil {
+		return
+	}
+
+	retries := 0
+	for _, remediation := range []*types.RemediationSpec{lifecycle.Install, lifecycle.Upgrade} {
+		if remediation != nil && remediation.Retries > retries {
+			retries = remediation.Retries
+		}
+	}
+	if retries > 0 {
+		limit := int32(retries)
+		spec.BackOffLimit = &limit
+	}
+
+	spec.Timeout = lifecycle.Timeout
+}
+
+// marshalValues renders values as the YAML document expected by valuesContent
+func marshalValues(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", nil
+	}
+
+	valuesBytes, err := yaml.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal values: %w", err)
+	}
+
+	return string(valuesBytes), nil
+}
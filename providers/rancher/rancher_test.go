@@ -2,8 +2,10 @@ package rancher
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
 	"github.com/kubed-io/krm-helm-fn/testutil"
 )
 
@@ -56,4 +58,178 @@ func TestRancherProvider_GenerateHelmChartFromExample(t *testing.T) {
 
 	// For rancher provider in hello world state, we just check basic structure
 	// Future tests will validate the full spec content
-}
\ No newline at end of file
+}
+
+// TestRancherProvider_GenerateHelmChartSpec tests the chart fields and the mapping of the portable sync settings
+func TestRancherProvider_GenerateHelmChartSpec(t *testing.T) {
+	// Load the rancher example files
+	exampleDir := filepath.Join("..", "..", "examples", "rancher")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Sync = &types.SyncSpec{CreateNamespace: true, Retry: &types.RetrySpec{Limit: 6}}
+
+	provider := NewRancherProvider()
+	helmChart, err := provider.GenerateHelmChart(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmChart failed: %v", err)
+	}
+
+	spec := helmChart.Spec
+	if spec.Chart != "hello-world" || spec.Repo != "https://helm.github.io/examples" || spec.Version != "0.1.0" || spec.TargetNamespace != "my-system" {
+		t.Errorf("Unexpected chart spec %+v", spec)
+	}
+
+	if spec.ValuesContent != "replicaCount: 2\n" {
+		t.Errorf("Expected values content 'replicaCount: 2', got %q", spec.ValuesContent)
+	}
+
+	if !spec.CreateNamespace {
+		t.Error("Expected createNamespace to be set")
+	}
+
+	if spec.BackOffLimit == nil || *spec.BackOffLimit != 6 {
+		t.Errorf("Expected backOffLimit 6, got %v", spec.BackOffLimit)
+	}
+}
+
+// TestRancherProvider_GenerateHelmChartSetValues tests that set values keep their type in the values content
+func TestRancherProvider_GenerateHelmChartSetValues(t *testing.T) {
+	// Load the rancher example files
+	exampleDir := filepath.Join("..", "..", "examples", "rancher")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	helmRelease.Spec.Set = []types.SetValue{
+		{Name: "replicaCount", Value: "3"},
+		{Name: "image.tag", Value: "1.2.3"},
+		{Name: "resources", Value: "null"},
+		{Name: "auth.password", ValueFrom: &types.ValueSource{SecretKeyRef: &types.KeySelector{Name: "db", Key: "password"}}},
+	}
+
+	provider := NewRancherProvider()
+	helmChart, err := provider.GenerateHelmChart(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmChart failed: %v", err)
+	}
+
+	expectedValues := "image:\n  tag: 1.2.3\nreplicaCount: 3\nresources: null\n"
+	if helmChart.Spec.ValuesContent != expectedValues {
+		t.Errorf("Expected values content %q, got %q", expectedValues, helmChart.Spec.ValuesContent)
+	}
+}
+
+// TestRancherProvider_GenerateHelmChartConfigFromExample tests the HelmChartConfig of a packaged chart
+func TestRancherProvider_GenerateHelmChartConfigFromExample(t *testing.T) {
+	// Load the rancher-helmchartconfig example files
+	exampleDir := filepath.Join("..", "..", "examples", "rancher-helmchartconfig")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewRancherProvider()
+	if !provider.UsesHelmChartConfig(helmRelease) {
+		t.Fatal("Expected the release to customize a packaged chart")
+	}
+
+	helmRelease.Spec.Set = []types.SetValue{{Name: "logs.general.level", Value: "DEBUG"}}
+
+	helmChartConfig, err := provider.GenerateHelmChartConfig(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmChartConfig failed: %v", err)
+	}
+
+	if helmChartConfig.Kind != "HelmChartConfig" || helmChartConfig.Name != "traefik" || helmChartConfig.Namespace != "kube-system" {
+		t.Errorf("Unexpected HelmChartConfig %s %s/%s", helmChartConfig.Kind, helmChartConfig.Namespace, helmChartConfig.Name)
+	}
+
+	expectedValues := "logs:\n  general:\n    level: DEBUG\nports:\n  websecure:\n    tls:\n      enabled: true\n"
+	if helmChartConfig.Spec.ValuesContent != expectedValues {
+		t.Errorf("Expected values content %q, got %q", expectedValues, helmChartConfig.Spec.ValuesContent)
+	}
+
+	if helmChartConfig.Spec.FailurePolicy != "abort" {
+		t.Errorf("Expected failure policy 'abort', got '%s'", helmChartConfig.Spec.FailurePolicy)
+	}
+
+	helmRelease.Spec.Rancher.FailurePolicy = "retry"
+	if _, err := provider.GenerateHelmChartConfig(helmRelease); err == nil {
+		t.Error("Expected an error for an unsupported failure policy")
+	}
+}
+
+// TestRancherProvider_GenerateHelmChartAdvanced tests the helm-controller specific fields and chart credentials
+func TestRancherProvider_GenerateHelmChartAdvanced(t *testing.T) {
+	// Load the rancher example files
+	exampleDir := filepath.Join("..", "..", "examples", "rancher")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	backOffLimit := int32(10)
+	helmRelease.Spec.Sync = &types.SyncSpec{Retry: &types.RetrySpec{Limit: 3}}
+	helmRelease.Spec.Chart.Auth = &types.ChartAuthSpec{SecretName: "repo-auth", CAConfigMapName: "repo-ca", PassCredentials: true, InsecureSkipTLSVerify: true}
+	helmRelease.Spec.Rancher = &types.RancherSpec{
+		JobImage:             "rancher/klipper-helm:v0.9.4",
+		Bootstrap:            true,
+		DockerRegistrySecret: "registry-auth",
+		PodSecurityContext:   map[string]interface{}{"runAsNonRoot": true},
+		BackOffLimit:         &backOffLimit,
+	}
+
+	provider := NewRancherProvider()
+	helmChart, err := provider.GenerateHelmChart(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateHelmChart failed: %v", err)
+	}
+
+	spec := helmChart.Spec
+	if spec.JobImage != "rancher/klipper-helm:v0.9.4" || !spec.Bootstrap {
+		t.Errorf("Unexpected job image '%s' or bootstrap %v", spec.JobImage, spec.Bootstrap)
+	}
+
+	if !reflect.DeepEqual(spec.AuthSecret, &types.LocalObjectReference{Name: "repo-auth"}) ||
+		!reflect.DeepEqual(spec.RepoCAConfigMap, &types.LocalObjectReference{Name: "repo-ca"}) ||
+		!reflect.DeepEqual(spec.DockerRegistrySecret, &types.LocalObjectReference{Name: "registry-auth"}) {
+		t.Errorf("Unexpected references %+v, %+v, %+v", spec.AuthSecret, spec.RepoCAConfigMap, spec.DockerRegistrySecret)
+	}
+
+	if !spec.AuthPassCredentials || !spec.InsecureSkipTLSVerify {
+		t.Errorf("Expected authPassCredentials and insecureSkipTLSVerify to be set")
+	}
+
+	if spec.PodSecurityContext["runAsNonRoot"] != true {
+		t.Errorf("Unexpected pod security context %v", spec.PodSecurityContext)
+	}
+
+	// The explicit backoff limit wins over the retry limit
+	if spec.BackOffLimit == nil || *spec.BackOffLimit != 10 {
+		t.Errorf("Expected backOffLimit 10, got %v", spec.BackOffLimit)
+	}
+}
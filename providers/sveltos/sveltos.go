@@ -0,0 +1,264 @@
+package sveltos
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/helmfn/values"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// SyncModeDriftDetection keeps deploying to the matching clusters and reverts changes made in them
+const SyncModeDriftDetection = "ContinuousWithDriftDetection"
+
+// SveltosProfile represents a Sveltos ClusterProfile or Profile resource, they only differ in scope
+type SveltosProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              SveltosProfileSpec `json:"spec,omitempty"`
+}
+
+// SveltosProfileSpec defines the charts deployed to the clusters matching the selector
+type SveltosProfileSpec struct {
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+	SyncMode        string               `json:"syncMode,omitempty"`
+	DependsOn       []string             `json:"dependsOn,omitempty"`
+	HelmCharts      []SveltosHelmChart   `json:"helmCharts"`
+}
+
+// SveltosHelmChart defines a chart deployed by a profile
+type SveltosHelmChart struct {
+	RepositoryURL             string                            `json:"repositoryURL"`
+	RepositoryName            string                            `json:"repositoryName"`
+	ChartName                 string                            `json:"chartName"`
+	ChartVersion              string                            `json:"chartVersion,omitempty"`
+	ReleaseName               string                            `json:"releaseName"`
+	ReleaseNamespace          string                            `json:"releaseNamespace"`
+	HelmChartAction           string                            `json:"helmChartAction"`
+	Values                    string                            `json:"values,omitempty"`
+	ValuesFrom                []SveltosValueFrom                `json:"valuesFrom,omitempty"`
+	Options                   *SveltosHelmOptions               `json:"options,omitempty"`
+	RegistryCredentialsConfig *SveltosRegistryCredentialsConfig `json:"registryCredentialsConfig,omitempty"`
+}
+
+// SveltosValueFrom references a Secret or ConfigMap whose keys are values files
+type SveltosValueFrom struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// SveltosHelmOptions defines how the chart is installed and upgraded
+type SveltosHelmOptions struct {
+	Timeout        string                     `json:"timeout,omitempty"`
+	InstallOptions *SveltosHelmInstallOptions `json:"installOptions,omitempty"`
+	UpgradeOptions *SveltosHelmUpgradeOptions `json:"upgradeOptions,omitempty"`
+}
+
+// SveltosHelmInstallOptions defines how the chart is installed
+type SveltosHelmInstallOptions struct {
+	CreateNamespace bool `json:"createNamespace,omitempty"`
+}
+
+// SveltosHelmUpgradeOptions defines how the chart is upgraded
+type SveltosHelmUpgradeOptions struct {
+	MaxHistory int `json:"maxHistory,omitempty"`
+}
+
+// SveltosRegistryCredentialsConfig defines how Sveltos authenticates to the chart repository
+type SveltosRegistryCredentialsConfig struct {
+	Credentials           *SveltosObjectReference `json:"credentials,omitempty"`
+	InsecureSkipTLSVerify bool                    `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// SveltosObjectReference references a resource in a namespace
+type SveltosObjectReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// SveltosProvider handles the transformation of HelmRelease to Sveltos profiles
+type SveltosProvider struct{}
+
+// NewSveltosProvider creates a new Sveltos provider instance
+func NewSveltosProvider() *SveltosProvider {
+	return &SveltosProvider{}
+}
+
+// GenerateProfile creates a Sveltos ClusterProfile deploying the chart to every cluster matching the
+// cluster selector of the HelmRelease, or a Profile limited to the clusters in the namespace of the
+// release. ClusterProfiles are named after the namespace and name of the release, so releases with the
+// same name in different namespaces do not collide, and dependencies on other releases become
+// dependencies on their profiles.
+func (p *SveltosProvider) GenerateProfile(helmRelease *types.HelmRelease) (*SveltosProfile, error) {
+	if helmRelease.Spec.ClusterSelector == nil {
+		return nil, fmt.Errorf("spec.clusterSelector is required to select the Sveltos clusters")
+	}
+
+	helmChart, err := helmChart(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &SveltosProfile{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "config.projectsveltos.io/v1beta1",
+			Kind:       "ClusterProfile",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ClusterProfileName(helmRelease.ObjectMeta.Namespace, helmRelease.ObjectMeta.Name),
+		},
+		Spec: SveltosProfileSpec{
+			ClusterSelector: *helmRelease.Spec.ClusterSelector,
+			HelmCharts:      []SveltosHelmChart{*helmChart},
+		},
+	}
+
+	namespaced := helmRelease.Spec.Sveltos != nil && helmRelease.Spec.Sveltos.Namespaced
+	if namespaced {
+		profile.Kind = "Profile"
+		profile.Name = helmRelease.ObjectMeta.Name
+		profile.Namespace = helmRelease.ObjectMeta.Namespace
+	}
+
+	for _, dependency := range helmRelease.Spec.DependsOn {
+		namespace := dependency.Namespace
+		if namespace == "" {
+			namespace = helmRelease.ObjectMeta.Namespace
+		}
+
+		// A Profile only depends on the Profiles of its own namespace
+		if namespaced {
+			if namespace != helmRelease.ObjectMeta.Namespace {
+				return nil, fmt.Errorf("a Profile cannot depend on %s/%s outside of its namespace %s", namespace, dependency.Name, helmRelease.ObjectMeta.Namespace)
+			}
+			profile.Spec.DependsOn = append(profile.Spec.DependsOn, dependency.Name)
+			continue
+		}
+		profile.Spec.DependsOn = append(profile.Spec.DependsOn, ClusterProfileName(namespace, dependency.Name))
+	}
+
+	if sync := helmRelease.Spec.Sync; sync != nil && sync.SelfHeal {
+		profile.Spec.SyncMode = SyncModeDriftDetection
+	}
+
+	return profile, nil
+}
+
+// ClusterProfileName returns the name of the ClusterProfile of the release with the given namespace and name
+func ClusterProfileName(namespace, name string) string {
+	return types.QualifiedName(namespace, name)
+}
+
+// helmChart builds the chart entry of a profile. OCI charts are addressed by their full reference, the
+// repository is registered under the release name.
+func helmChart(helmRelease *types.HelmRelease) (*SveltosHelmChart, error) {
+	chart := helmRelease.Spec.Chart
+	if chart.Repo == "" {
+		return nil, fmt.Errorf("spec.chart.repo is required to generate a Sveltos profile")
+	}
+
+	helmChart := &SveltosHelmChart{
+		RepositoryURL:    chart.Repo,
+		RepositoryName:   helmRelease.ObjectMeta.Name,
+		ChartName:        helmRelease.ObjectMeta.Name + "/" + chart.Name,
+		ChartVersion:     chart.Version,
+		ReleaseName:      helmRelease.ObjectMeta.Name,
+		ReleaseNamespace: helmRelease.ObjectMeta.Namespace,
+		HelmChartAction:  "Install",
+	}
+	if strings.HasPrefix(chart.Repo, "oci://") {
+		helmChart.RepositoryURL = strings.TrimSuffix(chart.Repo, "/") + "/" + chart.Name
+		helmChart.ChartName = helmChart.RepositoryURL
+	}
+
+	chartValues, err := valuesTemplate(helmRelease)
+	if err != nil {
+		return nil, err
+	}
+	helmChart.Values = chartValues
+
+	if sveltos := helmRelease.Spec.Sveltos; sveltos != nil {
+		for _, ref := range sveltos.ValuesFrom {
+			if ref.Name == "" {
+				return nil, fmt.Errorf("values reference without a name")
+			}
+			if ref.Kind != "Secret" && ref.Kind != "ConfigMap" {
+				return nil, fmt.Errorf("unsupported values reference kind: %s", ref.Kind)
+			}
+			helmChart.ValuesFrom = append(helmChart.ValuesFrom, SveltosValueFrom{
+				Kind:      ref.Kind,
+				Name:      ref.Name,
+				Namespace: helmRelease.ObjectMeta.Namespace,
+			})
+		}
+	}
+
+	options := &SveltosHelmOptions{}
+	applySyncSpec(options, helmRelease.Spec.Sync)
+	applyLifecycleSpec(options, helmRelease.Spec.Lifecycle)
+	if *options != (SveltosHelmOptions{}) {
+		helmChart.Options = options
+	}
+
+	if auth := helmRelease.Spec.Chart.Auth; auth != nil {
+		config := &SveltosRegistryCredentialsConfig{InsecureSkipTLSVerify: auth.InsecureSkipTLSVerify}
+		if auth.SecretName != "" {
+			config.Credentials = &SveltosObjectReference{Name: auth.SecretName, Namespace: helmRelease.ObjectMeta.Namespace}
+		}
+		if *config != (SveltosRegistryCredentialsConfig{}) {
+			helmChart.RegistryCredentialsConfig = config
+		}
+	}
+
+	return helmChart, nil
+}
+
+// valuesTemplate renders the values, with the literal set values merged in, as the template Sveltos
+// instantiates for each cluster. Template delimiters already in the values are escaped so they reach the
+// chart as they are, unless the values are meant to be templates.
+func valuesTemplate(helmRelease *types.HelmRelease) (string, error) {
+	if err := values.ValidateSet(helmRelease.Spec.Set); err != nil {
+		return "", err
+	}
+	chartValues, err := values.ApplySet(helmRelease.Spec.Values, helmRelease.Spec.Set)
+	if err != nil {
+		return "", err
+	}
+	if len(chartValues) == 0 {
+		return "", nil
+	}
+
+	valuesBytes, err := yaml.Marshal(chartValues)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal values: %w", err)
+	}
+
+	if sveltos := helmRelease.Spec.Sveltos; sveltos != nil && sveltos.RenderTemplates {
+		return string(valuesBytes), nil
+	}
+	return values.EscapeTemplate(string(valuesBytes)), nil
+}
+
+// applySyncSpec maps the portable sync settings which have a Sveltos helm option equivalent
+func applySyncSpec(options *SveltosHelmOptions, sync *types.SyncSpec) {
+	if sync == nil || !sync.CreateNamespace {
+		return
+	}
+
+	options.InstallOptions = &SveltosHelmInstallOptions{CreateNamespace: true}
+}
+
+// applyLifecycleSpec maps the portable lifecycle settings which have a Sveltos helm option equivalent
+func applyLifecycleSpec(options *SveltosHelmOptions, lifecycle *types.LifecycleSpec) {
+	if lifecycle == nil {
+		return
+	}
+
+	options.Timeout = lifecycle.Timeout
+	if lifecycle.MaxHistory != nil {
+		options.UpgradeOptions = &SveltosHelmUpgradeOptions{MaxHistory: *lifecycle.MaxHistory}
+	}
+}
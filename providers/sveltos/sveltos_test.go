@@ -0,0 +1,173 @@
+package sveltos
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubed-io/krm-helm-fn/helmfn/types"
+	"github.com/kubed-io/krm-helm-fn/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewSveltosProvider(t *testing.T) {
+	provider := NewSveltosProvider()
+	if provider == nil {
+		t.Error("NewSveltosProvider returned nil")
+	}
+}
+
+// TestSveltosProvider_GenerateProfileFromExample tests the Sveltos provider using example files
+func TestSveltosProvider_GenerateProfileFromExample(t *testing.T) {
+	// Load the sveltos example files
+	exampleDir := filepath.Join("..", "..", "examples", "sveltos")
+	example, err := testutil.LoadExampleFiles(exampleDir)
+	if err != nil {
+		t.Fatalf("Failed to load example files: %v", err)
+	}
+
+	// Parse the HelmRelease from the example
+	helmRelease, err := example.ParseHelmRelease()
+	if err != nil {
+		t.Fatalf("Failed to parse HelmRelease: %v", err)
+	}
+
+	provider := NewSveltosProvider()
+	profile, err := provider.GenerateProfile(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateProfile failed: %v", err)
+	}
+
+	if profile.APIVersion != "config.projectsveltos.io/v1beta1" || profile.Kind != "ClusterProfile" {
+		t.Errorf("Expected a config.projectsveltos.io/v1beta1 ClusterProfile, got %s %s", profile.APIVersion, profile.Kind)
+	}
+
+	// ClusterProfiles are cluster-scoped
+	if profile.Name != "my-system-my-app-c364f582" || profile.Namespace != "" {
+		t.Errorf("Expected the cluster-scoped my-system-my-app-c364f582, got %s/%s", profile.Namespace, profile.Name)
+	}
+
+	if profile.Spec.SyncMode != SyncModeDriftDetection {
+		t.Errorf("Expected syncMode '%s' with selfHeal, got '%s'", SyncModeDriftDetection, profile.Spec.SyncMode)
+	}
+
+	expectedChart := SveltosHelmChart{
+		RepositoryURL:    "https://helm.github.io/examples",
+		RepositoryName:   "my-app",
+		ChartName:        "my-app/hello-world",
+		ChartVersion:     "0.1.0",
+		ReleaseName:      "my-app",
+		ReleaseNamespace: "my-system",
+		HelmChartAction:  "Install",
+		Values:           "replicaCount: 2\n",
+		Options:          &SveltosHelmOptions{InstallOptions: &SveltosHelmInstallOptions{CreateNamespace: true}},
+	}
+	if len(profile.Spec.HelmCharts) != 1 || !reflect.DeepEqual(profile.Spec.HelmCharts[0], expectedChart) {
+		t.Errorf("Expected helm charts [%+v], got %+v", expectedChart, profile.Spec.HelmCharts)
+	}
+}
+
+// TestSveltosProvider_GenerateProfileSettings tests the namespaced profile, OCI charts, dependencies and values
+func TestSveltosProvider_GenerateProfileSettings(t *testing.T) {
+	maxHistory := 3
+	helmRelease := &types.HelmRelease{Spec: types.HelmReleaseSpec{
+		Chart: types.ChartSpec{
+			Name: "podinfo",
+			Repo: "oci://ghcr.io/stefanprodan/charts",
+			Auth: &types.ChartAuthSpec{SecretName: "registry-auth"},
+		},
+		Values:    map[string]interface{}{"ui": map[string]interface{}{"message": "{{ .Release.Name }}"}},
+		DependsOn: []types.ReleaseReference{{Name: "cert-manager"}},
+		Lifecycle: &types.LifecycleSpec{Timeout: "5m", MaxHistory: &maxHistory},
+		Sveltos: &types.SveltosSpec{
+			Namespaced: true,
+			ValuesFrom: []types.ValuesReference{{Kind: "ConfigMap", Name: "podinfo-values"}},
+		},
+	}}
+	helmRelease.Name = "podinfo"
+	helmRelease.Namespace = "tenant-a"
+
+	provider := NewSveltosProvider()
+	if _, err := provider.GenerateProfile(helmRelease); err == nil {
+		t.Error("Expected an error without spec.clusterSelector")
+	}
+
+	helmRelease.Spec.ClusterSelector = &metav1.LabelSelector{}
+	profile, err := provider.GenerateProfile(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateProfile failed: %v", err)
+	}
+
+	if profile.Kind != "Profile" || profile.Namespace != "tenant-a" {
+		t.Errorf("Expected a Profile in tenant-a, got %s in '%s'", profile.Kind, profile.Namespace)
+	}
+
+	if profile.Name != "podinfo" || !reflect.DeepEqual(profile.Spec.DependsOn, []string{"cert-manager"}) {
+		t.Errorf("Expected podinfo to depend on cert-manager, got %s depending on %v", profile.Name, profile.Spec.DependsOn)
+	}
+
+	chart := profile.Spec.HelmCharts[0]
+	if chart.RepositoryURL != "oci://ghcr.io/stefanprodan/charts/podinfo" || chart.ChartName != chart.RepositoryURL {
+		t.Errorf("Expected the OCI chart to be addressed by its reference, got '%s' and '%s'", chart.RepositoryURL, chart.ChartName)
+	}
+
+	// Chart templates in the values are not rendered by Sveltos
+	expectedValues := "ui:\n  message: '{{ \"{{\" }} .Release.Name {{ \"}}\" }}'\n"
+	if chart.Values != expectedValues {
+		t.Errorf("Expected values %q, got %q", expectedValues, chart.Values)
+	}
+
+	expectedValuesFrom := []SveltosValueFrom{{Kind: "ConfigMap", Name: "podinfo-values", Namespace: "tenant-a"}}
+	if !reflect.DeepEqual(chart.ValuesFrom, expectedValuesFrom) {
+		t.Errorf("Expected valuesFrom %+v, got %+v", expectedValuesFrom, chart.ValuesFrom)
+	}
+
+	expectedOptions := &SveltosHelmOptions{Timeout: "5m", UpgradeOptions: &SveltosHelmUpgradeOptions{MaxHistory: 3}}
+	if !reflect.DeepEqual(chart.Options, expectedOptions) {
+		t.Errorf("Expected options %+v, got %+v", expectedOptions, chart.Options)
+	}
+
+	expectedCredentials := &SveltosRegistryCredentialsConfig{Credentials: &SveltosObjectReference{Name: "registry-auth", Namespace: "tenant-a"}}
+	if !reflect.DeepEqual(chart.RegistryCredentialsConfig, expectedCredentials) {
+		t.Errorf("Expected registry credentials %+v, got %+v", expectedCredentials, chart.RegistryCredentialsConfig)
+	}
+}
+
+// TestSveltosProvider_DependsOnNamespaces tests the dependencies on releases of other namespaces
+func TestSveltosProvider_DependsOnNamespaces(t *testing.T) {
+	helmRelease := &types.HelmRelease{}
+	helmRelease.Name = "my-app"
+	helmRelease.Namespace = "tenant-a"
+	helmRelease.Spec = types.HelmReleaseSpec{
+		Chart:           types.ChartSpec{Name: "my-app", Repo: "https://charts.example.com"},
+		ClusterSelector: &metav1.LabelSelector{},
+		DependsOn: []types.ReleaseReference{
+			{Name: "cert-manager", Namespace: "cert-manager"},
+			{Name: "database"},
+		},
+	}
+
+	provider := NewSveltosProvider()
+	profile, err := provider.GenerateProfile(helmRelease)
+	if err != nil {
+		t.Fatalf("GenerateProfile failed: %v", err)
+	}
+
+	expected := []string{"cert-manager-cert-manager-47192c08", "tenant-a-database-9435e197"}
+	if !reflect.DeepEqual(profile.Spec.DependsOn, expected) {
+		t.Errorf("Expected to depend on %v, got %v", expected, profile.Spec.DependsOn)
+	}
+
+	helmRelease.Spec.Sveltos = &types.SveltosSpec{Namespaced: true}
+	if _, err := provider.GenerateProfile(helmRelease); err == nil {
+		t.Error("Expected an error for a Profile depending on another namespace")
+	}
+}
+
+// TestClusterProfileName tests that releases whose namespace and name join to the same string get different
+// ClusterProfiles
+func TestClusterProfileName(t *testing.T) {
+	if ClusterProfileName("a-b", "c") == ClusterProfileName("a", "b-c") {
+		t.Errorf("Expected a-b/c and a/b-c to get different names, both got %s", ClusterProfileName("a", "b-c"))
+	}
+}